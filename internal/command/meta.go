@@ -15,11 +15,13 @@ import (
 	logcache "code.cloudfoundry.org/go-log-cache"
 	"code.cloudfoundry.org/go-log-cache/rpc/logcache_v1"
 	flags "github.com/jessevdk/go-flags"
+	yaml "gopkg.in/yaml.v2"
 )
 
 type source struct {
 	GUID string `json:"guid"`
 	Name string `json:"name"`
+	Kind string `json:"-"`
 }
 
 type sourceInfo struct {
@@ -42,17 +44,153 @@ type servicesResponse struct {
 type Tailer func(sourceID string, start, end time.Time) []string
 
 type optionsFlags struct {
-	Scope       string `long:"scope"`
-	EnableNoise bool   `long:"noise"`
-	ShowGUID    bool   `long:"guid"`
+	Scope        string `long:"scope"`
+	EnableNoise  bool   `long:"noise"`
+	ShowGUID     bool   `long:"guid"`
+	Output       string `long:"output" short:"o"`
+	Watch        bool   `long:"watch" short:"w"`
+	Interval     string `long:"interval"`
+	NameCacheTTL string `long:"name-cache-ttl"`
+	NoNameCache  bool   `long:"no-name-cache"`
+	Timeout      string `long:"timeout"`
+	Filter       string `long:"filter"`
+	SortBy       string `long:"sort-by"`
+	Limit        int    `long:"limit"`
+}
+
+// MetaRow is the stable, renderer-agnostic representation of a single row of
+// Log Cache metadata. The `application`, `service`, and `platform` kinds
+// mirror the CAPI-enriched groupings Meta has always printed. Rate is
+// populated when --noise is passed, as envelopes seen by one tailer() call
+// per source over the last minute, or in --watch mode, where applyDeltas
+// sets it to envelopes per second since the previous refresh.
+type MetaRow struct {
+	SourceID             string    `json:"source_id" yaml:"source_id"`
+	Name                 string    `json:"name" yaml:"name"`
+	Kind                 string    `json:"kind" yaml:"kind"`
+	Count                int64     `json:"count" yaml:"count"`
+	Expired              int64     `json:"expired" yaml:"expired"`
+	CacheDurationSeconds float64   `json:"cache_duration_seconds" yaml:"cache_duration_seconds"`
+	NewestTimestamp      time.Time `json:"newest_timestamp" yaml:"newest_timestamp"`
+	OldestTimestamp      time.Time `json:"oldest_timestamp" yaml:"oldest_timestamp"`
+	Rate                 *int64    `json:"rate,omitempty" yaml:"rate,omitempty"`
+
+	// DeltaCount and DeltaExpired are only populated in --watch mode, where
+	// they report the change since the previous refresh.
+	DeltaCount   *int64 `json:"delta_count,omitempty" yaml:"delta_count,omitempty"`
+	DeltaExpired *int64 `json:"delta_expired,omitempty" yaml:"delta_expired,omitempty"`
+}
+
+// MetaRenderer writes a set of MetaRows to w in a particular output format.
+type MetaRenderer interface {
+	Render(w io.Writer, rows []MetaRow) error
+}
+
+// TableRenderer is the original human-oriented tabwriter rendering. ShowGUID
+// and EnableNoise mirror the --guid and --noise flags, since those affect
+// which columns are printed.
+type TableRenderer struct {
+	ShowGUID    bool
+	EnableNoise bool
+	// ShowDeltas adds ΔCount/ΔExpired/Rate columns populated by watchMeta
+	// between refreshes; it is independent of EnableNoise since --watch
+	// computes its own rate instead of calling the tailer.
+	ShowDeltas bool
+}
+
+func (t TableRenderer) Render(w io.Writer, rows []MetaRow) error {
+	headerArgs := []interface{}{"Source", "Count", "Expired", "Cache Duration"}
+	headerFormat := "%s\t%s\t%s\t%s\n"
+	tableFormat := "%s\t%d\t%d\t%s\n"
+
+	if t.ShowGUID {
+		headerArgs = append([]interface{}{"Source ID"}, headerArgs...)
+		headerFormat = "%s\t" + headerFormat
+		tableFormat = "%s\t" + tableFormat
+	}
+
+	if t.EnableNoise {
+		headerArgs = append(headerArgs, "Rate")
+		headerFormat = strings.Replace(headerFormat, "\n", "\t%s\n", 1)
+		tableFormat = strings.Replace(tableFormat, "\n", "\t%d\n", 1)
+	}
+
+	if t.ShowDeltas {
+		headerArgs = append(headerArgs, "ΔCount", "ΔExpired", "Rate/s")
+		headerFormat = strings.Replace(headerFormat, "\n", "\t%s\t%s\t%s\n", 1)
+		tableFormat = strings.Replace(tableFormat, "\n", "\t%s\t%s\t%s\n", 1)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 2, 2, ' ', 0)
+	fmt.Fprintf(tw, headerFormat, headerArgs...)
+
+	for _, row := range rows {
+		name := row.Name
+		if name == "" {
+			name = row.SourceID
+		}
+
+		args := []interface{}{name, row.Count, row.Expired, time.Duration(row.CacheDurationSeconds * float64(time.Second))}
+		if t.ShowGUID {
+			args = append([]interface{}{row.SourceID}, args...)
+		}
+		if t.EnableNoise {
+			var rate int64
+			if row.Rate != nil {
+				rate = *row.Rate
+			}
+			args = append(args, rate)
+		}
+		if t.ShowDeltas {
+			args = append(args, signedDelta(row.DeltaCount), signedDelta(row.DeltaExpired), rateString(row.Rate))
+		}
+
+		fmt.Fprintf(tw, tableFormat, args...)
+	}
+
+	return tw.Flush()
+}
+
+// JSONRenderer emits rows as a JSON array using the MetaRow schema.
+type JSONRenderer struct{}
+
+func (JSONRenderer) Render(w io.Writer, rows []MetaRow) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rows)
+}
+
+// YAMLRenderer emits rows as a YAML sequence using the MetaRow schema.
+type YAMLRenderer struct{}
+
+func (YAMLRenderer) Render(w io.Writer, rows []MetaRow) error {
+	return yaml.NewEncoder(w).Encode(rows)
+}
+
+func rendererFor(output string, opts optionsFlags) MetaRenderer {
+	switch output {
+	case "json":
+		return JSONRenderer{}
+	case "yaml":
+		return YAMLRenderer{}
+	default:
+		return TableRenderer{
+			ShowGUID:    opts.ShowGUID,
+			EnableNoise: opts.EnableNoise,
+		}
+	}
 }
 
 // Meta returns the metadata from Log Cache
 func Meta(ctx context.Context, cli plugin.CliConnection, tailer Tailer, args []string, c HTTPClient, log Logger, tableWriter io.Writer) {
 	opts := optionsFlags{
-		Scope:       "all",
-		EnableNoise: false,
-		ShowGUID:    false,
+		Scope:        "all",
+		EnableNoise:  false,
+		ShowGUID:     false,
+		Output:       "table",
+		Interval:     "2s",
+		NameCacheTTL: "1h",
+		Timeout:      "30s",
 	}
 
 	args, err := flags.ParseArgs(&opts, args)
@@ -69,6 +207,34 @@ func Meta(ctx context.Context, cli plugin.CliConnection, tailer Tailer, args []s
 		log.Fatalf("Scope must be 'platform', 'applications' or 'all'.")
 	}
 
+	output := strings.ToLower(opts.Output)
+	if invalidOutput(output) {
+		log.Fatalf("Output must be 'table', 'json' or 'yaml'.")
+	}
+
+	interval, err := time.ParseDuration(opts.Interval)
+	if err != nil {
+		log.Fatalf("Could not parse --interval: %s", err)
+	}
+
+	nameCacheTTL, err := time.ParseDuration(opts.NameCacheTTL)
+	if err != nil {
+		log.Fatalf("Could not parse --name-cache-ttl: %s", err)
+	}
+
+	timeout, err := time.ParseDuration(opts.Timeout)
+	if err != nil {
+		log.Fatalf("Could not parse --timeout: %s", err)
+	}
+
+	var filterExpr filterNode
+	if opts.Filter != "" {
+		filterExpr, err = parseFilterExpr(opts.Filter)
+		if err != nil {
+			log.Fatalf("Could not parse --filter: %s", err)
+		}
+	}
+
 	logCacheEndpoint, err := logCacheEndpoint(cli)
 	if err != nil {
 		log.Fatalf("Could not determine Log Cache endpoint: %s", err)
@@ -91,44 +257,74 @@ func Meta(ctx context.Context, cli plugin.CliConnection, tailer Tailer, args []s
 		logcache.WithHTTPClient(c),
 	)
 
+	username, err := cli.Username()
+	if err != nil {
+		log.Fatalf("Could not get username: %s", err)
+	}
+
+	cache, err := openNameCache(cli, opts.NoNameCache, nameCacheTTL)
+	if err != nil {
+		log.Fatalf("Could not open name cache: %s", err)
+	}
+
+	if opts.Watch {
+		watchMeta(ctx, client, cli, tailer, log, tableWriter, opts, scope, output, username, interval, cache, timeout, filterExpr)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
 	meta, err := client.Meta(ctx)
 	if err != nil {
 		log.Fatalf("Failed to read Meta information: %s", err)
 	}
 
-	resources, err := getSourceInfo(meta, cli)
+	resources, partial, err := getSourceInfo(ctx, meta, cli, cache)
 	if err != nil {
 		log.Fatalf("Failed to read application information: %s", err)
 	}
 
-	username, err := cli.Username()
-	if err != nil {
-		log.Fatalf("Could not get username: %s", err)
+	if cache != nil {
+		if err := cache.save(); err != nil {
+			log.Printf("Could not persist name cache: %s", err)
+		}
 	}
 
-	fmt.Fprintf(tableWriter, fmt.Sprintf(
-		"Retrieving log cache metadata as %s...\n\n",
-		username,
-	))
+	if output == "table" {
+		fmt.Fprintf(tableWriter, fmt.Sprintf(
+			"Retrieving log cache metadata as %s...\n\n",
+			username,
+		))
+	}
 
-	headerArgs := []interface{}{"Source", "Count", "Expired", "Cache Duration"}
-	headerFormat := "%s\t%s\t%s\t%s\n"
-	tableFormat := "%s\t%d\t%d\t%s\n"
+	rows := buildMetaRows(meta, resources, scope, opts.EnableNoise, tailer)
 
-	if opts.ShowGUID {
-		headerArgs = append([]interface{}{"Source ID"}, headerArgs...)
-		headerFormat = "%s\t" + headerFormat
-		tableFormat = "%s\t" + tableFormat
+	rows, err = filterRows(rows, filterExpr)
+	if err != nil {
+		log.Fatalf("Could not apply --filter: %s", err)
+	}
+	if err := sortRows(rows, opts.SortBy); err != nil {
+		log.Fatalf("Could not apply --sort-by: %s", err)
 	}
+	rows = limitRows(rows, opts.Limit)
 
-	if opts.EnableNoise {
-		headerArgs = append(headerArgs, "Rate")
-		headerFormat = strings.Replace(headerFormat, "\n", "\t%s\n", 1)
-		tableFormat = strings.Replace(tableFormat, "\n", "\t%d\n", 1)
+	renderer := rendererFor(output, opts)
+	if err := renderer.Render(tableWriter, rows); err != nil {
+		log.Fatalf("Failed to render Meta information: %s", err)
 	}
 
-	tw := tabwriter.NewWriter(tableWriter, 0, 2, 2, ' ', 0)
-	fmt.Fprintf(tw, headerFormat, headerArgs...)
+	if partial && output == "table" {
+		fmt.Fprintln(tableWriter, "\n(partial: deadline exceeded)")
+	}
+}
+
+// buildMetaRows walks the CAPI-enriched resource list the same way the
+// original table rendering did, producing one MetaRow per printed source
+// and removing it from meta so the remaining map holds only unmatched
+// sourceIDs (used for the applications/platform fallback buckets below).
+func buildMetaRows(meta map[string]*logcache_v1.MetaInfo, resources []source, scope string, enableNoise bool, tailer Tailer) []MetaRow {
+	var rows []MetaRow
 
 	for _, app := range resources {
 		m, ok := meta[app.GUID]
@@ -137,17 +333,7 @@ func Meta(ctx context.Context, cli plugin.CliConnection, tailer Tailer, args []s
 		}
 		delete(meta, app.GUID)
 		if scope == "applications" || scope == "all" {
-			args := []interface{}{app.Name, m.Count, m.Expired, cacheDuration(m)}
-			if opts.ShowGUID {
-				args = append([]interface{}{app.GUID}, args...)
-			}
-			if opts.EnableNoise {
-				end := time.Now()
-				start := end.Add(-time.Minute)
-				args = append(args, len(tailer(app.GUID, start, end)))
-			}
-
-			fmt.Fprintf(tw, tableFormat, args...)
+			rows = append(rows, newMetaRow(app.GUID, app.Name, app.Kind, m, enableNoise, tailer))
 		}
 	}
 
@@ -157,16 +343,7 @@ func Meta(ctx context.Context, cli plugin.CliConnection, tailer Tailer, args []s
 	if scope == "applications" || scope == "all" {
 		for sourceID, m := range meta {
 			if idRegexp.MatchString(sourceID) {
-				args := []interface{}{sourceID, m.Count, m.Expired, cacheDuration(m)}
-				if opts.ShowGUID {
-					args = append([]interface{}{sourceID}, args...)
-				}
-				if opts.EnableNoise {
-					end := time.Now()
-					start := end.Add(-time.Minute)
-					args = append(args, len(tailer(sourceID, start, end)))
-				}
-				fmt.Fprintf(tw, tableFormat, args...)
+				rows = append(rows, newMetaRow(sourceID, "", "application", m, enableNoise, tailer))
 			}
 		}
 	}
@@ -174,26 +351,44 @@ func Meta(ctx context.Context, cli plugin.CliConnection, tailer Tailer, args []s
 	if scope == "platform" || scope == "all" {
 		for sourceID, m := range meta {
 			if !idRegexp.MatchString(sourceID) {
-				args := []interface{}{sourceID, m.Count, m.Expired, cacheDuration(m)}
-				if opts.ShowGUID {
-					args = append([]interface{}{sourceID}, args...)
-				}
-				if opts.EnableNoise {
-					end := time.Now()
-					start := end.Add(-time.Minute)
-					args = append(args, len(tailer(sourceID, start, end)))
-				}
-
-				fmt.Fprintf(tw, tableFormat, args...)
+				rows = append(rows, newMetaRow(sourceID, "", "platform", m, enableNoise, tailer))
 			}
 		}
 	}
 
-	tw.Flush()
+	return rows
 }
 
-func getSourceInfo(metaInfo map[string]*logcache_v1.MetaInfo, cli plugin.CliConnection) ([]source, error) {
-	var resources []source
+func newMetaRow(sourceID, name, kind string, m *logcache_v1.MetaInfo, enableNoise bool, tailer Tailer) MetaRow {
+	row := MetaRow{
+		SourceID:             sourceID,
+		Name:                 name,
+		Kind:                 kind,
+		Count:                m.Count,
+		Expired:              m.Expired,
+		CacheDurationSeconds: cacheDuration(m).Seconds(),
+		NewestTimestamp:      time.Unix(0, m.NewestTimestamp),
+		OldestTimestamp:      time.Unix(0, m.OldestTimestamp),
+	}
+
+	if enableNoise {
+		end := time.Now()
+		start := end.Add(-time.Minute)
+		rate := int64(len(tailer(sourceID, start, end)))
+		row.Rate = &rate
+	}
+
+	return row
+}
+
+// getSourceInfo resolves GUID metadata to human-readable names via CAPI,
+// consulting cache first when one is provided so that only cache misses
+// incur a /v3/apps or /v2/service_instances round trip. A nil cache
+// preserves the original always-fetch behavior. Batches are fetched through
+// a bounded worker pool (see fetchBatches) so ctx's deadline is actually
+// reachable against a large foundation; if ctx expires mid-flight, whatever
+// resources were already collected are returned alongside partial=true.
+func getSourceInfo(ctx context.Context, metaInfo map[string]*logcache_v1.MetaInfo, cli plugin.CliConnection, cache *nameCache) (resources []source, partial bool, err error) {
 	var sourceIDs []string
 
 	meta := make(map[string]int)
@@ -202,30 +397,58 @@ func getSourceInfo(metaInfo map[string]*logcache_v1.MetaInfo, cli plugin.CliConn
 		sourceIDs = append(sourceIDs, k)
 	}
 
-	for len(sourceIDs) > 0 {
-		var r sourceInfo
-		n := 50
-		if len(sourceIDs) < 50 {
-			n = len(sourceIDs)
+	misses := sourceIDs
+	if cache != nil {
+		misses = nil
+		for _, id := range sourceIDs {
+			e, ok := cache.lookup(id)
+			if !ok {
+				misses = append(misses, id)
+				continue
+			}
+			delete(meta, id)
+			resources = append(resources, source{GUID: id, Name: e.Name, Kind: e.Kind})
 		}
+	}
 
+	now := time.Now()
+	appResources, partial, err := fetchBatches(ctx, chunkIDs(misses, 50), func(batch []string) ([]source, error) {
 		lines, err := cli.CliCommandWithoutTerminalOutput(
 			"curl",
-			"/v3/apps?guids="+strings.Join(sourceIDs[0:n], ","),
+			"/v3/apps?guids="+strings.Join(batch, ","),
 		)
 		if err != nil {
 			return nil, err
 		}
 
-		sourceIDs = sourceIDs[n:]
-		rb := strings.Join(lines, "")
-		err = json.NewDecoder(strings.NewReader(rb)).Decode(&r)
-		if err != nil {
+		var r sourceInfo
+		if err := json.NewDecoder(strings.NewReader(strings.Join(lines, ""))).Decode(&r); err != nil {
 			return nil, err
 		}
 
-		resources = append(resources, r.Resources...)
+		found := make(map[string]bool, len(r.Resources))
+		for i := range r.Resources {
+			r.Resources[i].Kind = "application"
+			found[r.Resources[i].GUID] = true
+			if cache != nil {
+				cache.put(r.Resources[i].GUID, r.Resources[i].Name, "application", now)
+			}
+		}
+
+		if cache != nil {
+			for _, id := range batch {
+				if !found[id] {
+					cache.invalidate(id)
+				}
+			}
+		}
+
+		return r.Resources, nil
+	})
+	if err != nil {
+		return nil, false, err
 	}
+	resources = append(resources, appResources...)
 
 	for _, res := range resources {
 		delete(meta, res.GUID)
@@ -235,54 +458,82 @@ func getSourceInfo(metaInfo map[string]*logcache_v1.MetaInfo, cli plugin.CliConn
 		s = append(s, id)
 	}
 
-	services, err := getServiceInfo(s, cli)
+	if partial {
+		// The apps fetch already ran out of time; don't spend any more of
+		// the deadline guessing at services.
+		return resources, true, nil
+	}
+
+	services, servicesPartial, err := getServiceInfo(ctx, s, cli, cache)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 	resources = append(resources, services...)
 
-	return resources, nil
+	return resources, servicesPartial, nil
 }
 
-func getServiceInfo(sourceIDs []string, cli plugin.CliConnection) ([]source, error) {
-	var (
-		responseBodies []string
-		resources      []source
-	)
-
-	for len(sourceIDs) > 0 {
-		n := 50
-		if len(sourceIDs) < 50 {
-			n = len(sourceIDs)
-		}
+// getServiceInfo is getSourceInfo's counterpart for the sourceIDs that
+// weren't apps; sourceIDs here have already been filtered through the
+// cache by getSourceInfo, so every batch here is a genuine cache miss.
+func getServiceInfo(ctx context.Context, sourceIDs []string, cli plugin.CliConnection, cache *nameCache) ([]source, bool, error) {
+	now := time.Now()
 
+	return fetchBatches(ctx, chunkIDs(sourceIDs, 50), func(batch []string) ([]source, error) {
 		lines, err := cli.CliCommandWithoutTerminalOutput(
 			"curl",
-			"/v2/service_instances?guids="+strings.Join(sourceIDs[0:n], ","),
+			"/v2/service_instances?guids="+strings.Join(batch, ","),
 		)
 		if err != nil {
 			return nil, err
 		}
 
-		sourceIDs = sourceIDs[n:]
-		responseBodies = append(responseBodies, strings.Join(lines, ""))
-	}
-
-	for _, rb := range responseBodies {
 		var r servicesResponse
-		err := json.NewDecoder(strings.NewReader(rb)).Decode(&r)
-		if err != nil {
+		if err := json.NewDecoder(strings.NewReader(strings.Join(lines, ""))).Decode(&r); err != nil {
 			return nil, err
 		}
+
+		var batchResources []source
+		found := make(map[string]bool, len(r.Resources))
 		for _, res := range r.Resources {
-			resources = append(resources, source{
+			found[res.Metadata.GUID] = true
+			batchResources = append(batchResources, source{
 				GUID: res.Metadata.GUID,
 				Name: res.Entity.Name,
+				Kind: "service",
 			})
+			if cache != nil {
+				cache.put(res.Metadata.GUID, res.Entity.Name, "service", now)
+			}
+		}
+
+		if cache != nil {
+			for _, id := range batch {
+				if !found[id] {
+					cache.invalidate(id)
+				}
+			}
 		}
+
+		return batchResources, nil
+	})
+}
+
+func signedDelta(d *int64) string {
+	if d == nil {
+		return "-"
 	}
+	if *d >= 0 {
+		return fmt.Sprintf("+%d", *d)
+	}
+	return fmt.Sprintf("%d", *d)
+}
 
-	return resources, nil
+func rateString(rate *int64) string {
+	if rate == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%d", *rate)
 }
 
 func cacheDuration(m *logcache_v1.MetaInfo) time.Duration {
@@ -317,6 +568,28 @@ func logCacheEndpoint(cli plugin.CliConnection) (string, error) {
 	return strings.Replace(apiEndpoint, "api", "log-cache", 1), nil
 }
 
+// openNameCache loads the on-disk CAPI name cache for the foundation cli is
+// targeting. It returns a nil cache (not an error) when --no-name-cache was
+// passed, which getSourceInfo/getServiceInfo treat as "always fetch", the
+// pre-cache behavior.
+func openNameCache(cli plugin.CliConnection, disabled bool, ttl time.Duration) (*nameCache, error) {
+	if disabled {
+		return nil, nil
+	}
+
+	apiEndpoint, err := cli.ApiEndpoint()
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := defaultNameCachePath()
+	if err != nil {
+		return nil, err
+	}
+
+	return loadNameCache(path, apiEndpoint, ttl)
+}
+
 func invalidScope(scope string) bool {
 	validScopes := []string{"platform", "applications", "all"}
 
@@ -332,3 +605,19 @@ func invalidScope(scope string) bool {
 
 	return true
 }
+
+func invalidOutput(output string) bool {
+	validOutputs := []string{"table", "json", "yaml"}
+
+	if output == "" {
+		return false
+	}
+
+	for _, o := range validOutputs {
+		if output == o {
+			return false
+		}
+	}
+
+	return true
+}
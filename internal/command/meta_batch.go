@@ -0,0 +1,109 @@
+package command
+
+import (
+	"context"
+	"sync"
+)
+
+// capiWorkerPoolSize bounds how many CAPI batch requests getSourceInfo and
+// getServiceInfo have in flight at once, so a --timeout is actually
+// reachable against a foundation with thousands of sources instead of
+// running every batch strictly sequentially.
+const capiWorkerPoolSize = 4
+
+// chunkIDs splits ids into groups of at most size, preserving order. CAPI's
+// /v3/apps and /v2/service_instances endpoints cap how many guids can be
+// queried in one request, so callers fetch one chunk per request.
+func chunkIDs(ids []string, size int) [][]string {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var chunks [][]string
+	for len(ids) > 0 {
+		n := size
+		if len(ids) < n {
+			n = len(ids)
+		}
+		chunks = append(chunks, ids[:n])
+		ids = ids[n:]
+	}
+
+	return chunks
+}
+
+// fetchBatches runs fn once per batch using a bounded worker pool,
+// aggregating the results. If ctx is cancelled before every batch has run,
+// fetchBatches stops dispatching new work and returns whatever was already
+// collected with partial=true instead of blocking out the deadline. A fn
+// error aborts the whole fetch immediately, cancelling an internal copy of
+// ctx so the dispatcher and any workers still blocked sending to results
+// unblock and exit instead of leaking.
+func fetchBatches(ctx context.Context, batches [][]string, fn func(batch []string) ([]source, error)) ([]source, bool, error) {
+	if len(batches) == 0 {
+		return nil, false, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := capiWorkerPoolSize
+	if workers > len(batches) {
+		workers = len(batches)
+	}
+
+	jobs := make(chan []string)
+	type result struct {
+		sources []source
+		err     error
+	}
+	results := make(chan result)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for batch := range jobs {
+				sources, err := fn(batch)
+				select {
+				case results <- result{sources: sources, err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, batch := range batches {
+			select {
+			case jobs <- batch:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var resources []source
+	dispatched := 0
+	for r := range results {
+		dispatched++
+		if r.err != nil {
+			return resources, false, r.err
+		}
+		resources = append(resources, r.sources...)
+	}
+
+	if dispatched < len(batches) {
+		return resources, true, nil
+	}
+
+	return resources, false, nil
+}
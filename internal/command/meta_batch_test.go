@@ -0,0 +1,76 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("chunkIDs", func() {
+	It("splits into groups of at most size, preserving order", func() {
+		ids := []string{"a", "b", "c", "d", "e"}
+
+		Expect(chunkIDs(ids, 2)).To(Equal([][]string{
+			{"a", "b"}, {"c", "d"}, {"e"},
+		}))
+	})
+
+	It("returns nil for an empty input", func() {
+		Expect(chunkIDs(nil, 2)).To(BeNil())
+	})
+})
+
+var _ = Describe("fetchBatches", func() {
+	It("aggregates the results of every batch", func() {
+		resources, partial, err := fetchBatches(
+			context.Background(),
+			[][]string{{"a"}, {"b"}},
+			func(batch []string) ([]source, error) {
+				return []source{{GUID: batch[0]}}, nil
+			},
+		)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(partial).To(BeFalse())
+		Expect(resources).To(ConsistOf(source{GUID: "a"}, source{GUID: "b"}))
+	})
+
+	It("aborts immediately when a batch errors", func() {
+		boom := errors.New("boom")
+
+		_, _, err := fetchBatches(
+			context.Background(),
+			[][]string{{"a"}, {"b"}},
+			func(batch []string) ([]source, error) {
+				if batch[0] == "b" {
+					return nil, boom
+				}
+				return []source{{GUID: batch[0]}}, nil
+			},
+		)
+
+		Expect(err).To(Equal(boom))
+	})
+
+	It("reports partial when ctx is cancelled before every batch runs", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		var dispatched int32
+		_, partial, err := fetchBatches(
+			ctx,
+			[][]string{{"a"}, {"b"}, {"c"}, {"d"}},
+			func(batch []string) ([]source, error) {
+				if atomic.AddInt32(&dispatched, 1) == 1 {
+					cancel()
+				}
+				return []source{{GUID: batch[0]}}, nil
+			},
+		)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(partial).To(BeTrue())
+	})
+})
@@ -0,0 +1,220 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/cli/plugin"
+	logcache "code.cloudfoundry.org/go-log-cache"
+	flags "github.com/jessevdk/go-flags"
+)
+
+type metaExporterFlags struct {
+	Addr            string `long:"addr"`
+	RefreshInterval string `long:"refresh-interval"`
+	Timeout         string `long:"timeout"`
+	EnableNoise     bool   `long:"noise"`
+	NameCacheTTL    string `long:"name-cache-ttl"`
+	NoNameCache     bool   `long:"no-name-cache"`
+}
+
+// ServeMetaExporter parses CLI args and blocks serving a Prometheus/OpenMetrics
+// text-format scrape endpoint built from the same information Meta renders,
+// so operators can trend cache pressure in Prometheus/Grafana instead of
+// writing a bespoke collector. It honors LOG_CACHE_ADDR/LOG_CACHE_SKIP_AUTH
+// exactly like Meta.
+func ServeMetaExporter(ctx context.Context, cli plugin.CliConnection, tailer Tailer, args []string, c HTTPClient, log Logger, output io.Writer) {
+	opts := metaExporterFlags{
+		Addr:            ":9186",
+		RefreshInterval: "15s",
+		Timeout:         "30s",
+		NameCacheTTL:    "1h",
+	}
+
+	args, err := flags.ParseArgs(&opts, args)
+	if err != nil {
+		log.Fatalf("Could not parse flags: %s", err)
+	}
+
+	if len(args) > 0 {
+		log.Fatalf("Invalid arguments, expected 0, got %d.", len(args))
+	}
+
+	refreshInterval, err := time.ParseDuration(opts.RefreshInterval)
+	if err != nil {
+		log.Fatalf("Could not parse --refresh-interval: %s", err)
+	}
+
+	timeout, err := time.ParseDuration(opts.Timeout)
+	if err != nil {
+		log.Fatalf("Could not parse --timeout: %s", err)
+	}
+
+	nameCacheTTL, err := time.ParseDuration(opts.NameCacheTTL)
+	if err != nil {
+		log.Fatalf("Could not parse --name-cache-ttl: %s", err)
+	}
+
+	logCacheEndpoint, err := logCacheEndpoint(cli)
+	if err != nil {
+		log.Fatalf("Could not determine Log Cache endpoint: %s", err)
+	}
+
+	if strings.ToLower(os.Getenv("LOG_CACHE_SKIP_AUTH")) != "true" {
+		token, err := cli.AccessToken()
+		if err != nil {
+			log.Fatalf("Unable to get Access Token: %s", err)
+		}
+
+		c = &tokenHTTPClient{
+			c:           c,
+			accessToken: token,
+		}
+	}
+
+	client := logcache.NewClient(
+		logCacheEndpoint,
+		logcache.WithHTTPClient(c),
+	)
+
+	cache, err := openNameCache(cli, opts.NoNameCache, nameCacheTTL)
+	if err != nil {
+		log.Fatalf("Could not open name cache: %s", err)
+	}
+
+	exporter := NewMetaExporter(client, cli, tailer, opts.EnableNoise, refreshInterval, timeout, cache)
+	go exporter.Start(ctx)
+
+	fmt.Fprintf(output, "Serving Log Cache metrics on %s/metrics\n", opts.Addr)
+	if err := http.ListenAndServe(opts.Addr, exporter); err != nil {
+		log.Fatalf("Metrics server failed: %s", err)
+	}
+}
+
+// MetaExporter periodically refreshes Log Cache's Meta information via
+// client.Meta and the CAPI enrichment helpers, and exposes the result as
+// Prometheus/OpenMetrics text format on /metrics.
+type MetaExporter struct {
+	client          *logcache.Client
+	cli             plugin.CliConnection
+	tailer          Tailer
+	enableNoise     bool
+	refreshInterval time.Duration
+	timeout         time.Duration
+	cache           *nameCache
+
+	mu   sync.RWMutex
+	rows []MetaRow
+}
+
+// NewMetaExporter constructs a MetaExporter. Call Start to begin the refresh
+// loop; the exporter itself is an http.Handler serving /metrics. cache may
+// be nil to disable the on-disk CAPI name cache. timeout bounds each
+// individual refresh the way --timeout bounds a one-shot Meta call, so a
+// slow or stuck CAPI request can't stall refreshes for the life of the
+// process.
+func NewMetaExporter(client *logcache.Client, cli plugin.CliConnection, tailer Tailer, enableNoise bool, refreshInterval, timeout time.Duration, cache *nameCache) *MetaExporter {
+	return &MetaExporter{
+		client:          client,
+		cli:             cli,
+		tailer:          tailer,
+		enableNoise:     enableNoise,
+		refreshInterval: refreshInterval,
+		timeout:         timeout,
+		cache:           cache,
+	}
+}
+
+// Start refreshes immediately and then on refreshInterval until ctx is done.
+// It is meant to be run in its own goroutine alongside ListenAndServe(addr, e).
+func (e *MetaExporter) Start(ctx context.Context) {
+	e.refresh(ctx)
+
+	ticker := time.NewTicker(e.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.refresh(ctx)
+		}
+	}
+}
+
+func (e *MetaExporter) refresh(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	rows, _, err := fetchMetaRows(ctx, e.client, e.cli, "all", e.enableNoise, e.tailer, e.cache)
+	if err != nil {
+		// Keep serving the last good scrape rather than going empty on a
+		// single flaky refresh.
+		return
+	}
+
+	if e.cache != nil {
+		_ = e.cache.save()
+	}
+
+	e.mu.Lock()
+	e.rows = rows
+	e.mu.Unlock()
+}
+
+func (e *MetaExporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/metrics" {
+		http.NotFound(w, r)
+		return
+	}
+
+	e.mu.RLock()
+	rows := e.rows
+	e.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	writePrometheusMetrics(w, rows)
+}
+
+func writePrometheusMetrics(w io.Writer, rows []MetaRow) {
+	fmt.Fprintln(w, "# HELP log_cache_source_envelope_count Number of envelopes currently cached for a source.")
+	fmt.Fprintln(w, "# TYPE log_cache_source_envelope_count gauge")
+	for _, row := range rows {
+		fmt.Fprintf(w, "log_cache_source_envelope_count{source_id=%q,name=%q,kind=%q} %d\n", row.SourceID, row.Name, row.Kind, row.Count)
+	}
+
+	fmt.Fprintln(w, "# HELP log_cache_source_expired_total Number of envelopes expired out of the cache for a source.")
+	fmt.Fprintln(w, "# TYPE log_cache_source_expired_total counter")
+	for _, row := range rows {
+		fmt.Fprintf(w, "log_cache_source_expired_total{source_id=%q,name=%q,kind=%q} %d\n", row.SourceID, row.Name, row.Kind, row.Expired)
+	}
+
+	fmt.Fprintln(w, "# HELP log_cache_source_cache_duration_seconds Span between the oldest and newest cached envelope for a source.")
+	fmt.Fprintln(w, "# TYPE log_cache_source_cache_duration_seconds gauge")
+	for _, row := range rows {
+		fmt.Fprintf(w, "log_cache_source_cache_duration_seconds{source_id=%q,name=%q,kind=%q} %f\n", row.SourceID, row.Name, row.Kind, row.CacheDurationSeconds)
+	}
+
+	var withRate []MetaRow
+	for _, row := range rows {
+		if row.Rate != nil {
+			withRate = append(withRate, row)
+		}
+	}
+	if len(withRate) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "# HELP log_cache_source_rate_envelopes_per_minute Envelopes ingested per minute for a source, sampled via the tailer.")
+	fmt.Fprintln(w, "# TYPE log_cache_source_rate_envelopes_per_minute gauge")
+	for _, row := range withRate {
+		fmt.Fprintf(w, "log_cache_source_rate_envelopes_per_minute{source_id=%q,name=%q,kind=%q} %d\n", row.SourceID, row.Name, row.Kind, *row.Rate)
+	}
+}
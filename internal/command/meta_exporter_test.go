@@ -0,0 +1,48 @@
+package command
+
+import (
+	"bytes"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("writePrometheusMetrics", func() {
+	It("emits gauge/counter families for every row", func() {
+		rows := []MetaRow{
+			{SourceID: "source-id-1", Name: "my-app", Kind: "application", Count: 10, Expired: 1, CacheDurationSeconds: 60},
+		}
+
+		var buf bytes.Buffer
+		writePrometheusMetrics(&buf, rows)
+
+		Expect(buf.String()).To(ContainSubstring(`log_cache_source_envelope_count{source_id="source-id-1",name="my-app",kind="application"} 10`))
+		Expect(buf.String()).To(ContainSubstring(`log_cache_source_expired_total{source_id="source-id-1",name="my-app",kind="application"} 1`))
+		Expect(buf.String()).To(ContainSubstring(`log_cache_source_cache_duration_seconds{source_id="source-id-1",name="my-app",kind="application"} 60.000000`))
+	})
+
+	It("only emits the rate family when a row carries a rate", func() {
+		rate := int64(7)
+		rows := []MetaRow{
+			{SourceID: "source-id-1", Count: 10, Rate: &rate},
+			{SourceID: "source-id-2", Count: 5},
+		}
+
+		var buf bytes.Buffer
+		writePrometheusMetrics(&buf, rows)
+
+		rateSection := buf.String()[strings.Index(buf.String(), "log_cache_source_rate_envelopes_per_minute{"):]
+		Expect(rateSection).To(ContainSubstring(`log_cache_source_rate_envelopes_per_minute{source_id="source-id-1",name="",kind=""} 7`))
+		Expect(rateSection).ToNot(ContainSubstring(`source-id-2`))
+	})
+
+	It("omits the rate metric family entirely when nothing carries a rate", func() {
+		rows := []MetaRow{{SourceID: "source-id-1", Count: 10}}
+
+		var buf bytes.Buffer
+		writePrometheusMetrics(&buf, rows)
+
+		Expect(buf.String()).ToNot(ContainSubstring("log_cache_source_rate_envelopes_per_minute"))
+	})
+})
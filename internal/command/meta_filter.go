@@ -0,0 +1,515 @@
+package command
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// filterColumns enumerates the MetaRow fields --filter and --sort-by may
+// reference. Keeping this list in one place means both flags accept and
+// reject exactly the same column names.
+var filterColumns = map[string]bool{
+	"name":           true,
+	"source_id":      true,
+	"kind":           true,
+	"count":          true,
+	"expired":        true,
+	"cache_duration": true,
+	"rate":           true,
+}
+
+var filterStringColumns = map[string]bool{
+	"name":      true,
+	"source_id": true,
+	"kind":      true,
+}
+
+var filterStringOps = map[string]bool{"==": true, "!=": true, "=~": true, "!~": true}
+var filterNumericOps = map[string]bool{">": true, "<": true, ">=": true, "<=": true, "==": true, "!=": true}
+
+// filterNode is one node of a parsed --filter expression.
+type filterNode interface {
+	eval(row MetaRow) (bool, error)
+}
+
+type andNode struct{ left, right filterNode }
+
+func (n andNode) eval(row MetaRow) (bool, error) {
+	l, err := n.left.eval(row)
+	if err != nil || !l {
+		return false, err
+	}
+	return n.right.eval(row)
+}
+
+type orNode struct{ left, right filterNode }
+
+func (n orNode) eval(row MetaRow) (bool, error) {
+	l, err := n.left.eval(row)
+	if err != nil || l {
+		return l, err
+	}
+	return n.right.eval(row)
+}
+
+type notNode struct{ inner filterNode }
+
+func (n notNode) eval(row MetaRow) (bool, error) {
+	v, err := n.inner.eval(row)
+	return !v, err
+}
+
+// comparisonNode is a single `column op value` leaf, e.g. `expired > 100` or
+// `kind =~ "serv.*"`. value/valueKind hold the literal text and lexer token
+// kind so eval can defer number vs. duration vs. string parsing until it
+// knows which column (and therefore which type) it's comparing against.
+type comparisonNode struct {
+	column    string
+	op        string
+	value     string
+	valueKind tokenKind
+}
+
+func (n comparisonNode) eval(row MetaRow) (bool, error) {
+	switch n.column {
+	case "name":
+		return n.evalString(row.Name)
+	case "source_id":
+		return n.evalString(row.SourceID)
+	case "kind":
+		return n.evalString(row.Kind)
+	case "count":
+		return n.evalNumber(float64(row.Count))
+	case "expired":
+		return n.evalNumber(float64(row.Expired))
+	case "cache_duration":
+		return n.evalNumber(row.CacheDurationSeconds)
+	case "rate":
+		var rate float64
+		if row.Rate != nil {
+			rate = float64(*row.Rate)
+		}
+		return n.evalNumber(rate)
+	default:
+		return false, fmt.Errorf("unknown filter column %q", n.column)
+	}
+}
+
+func (n comparisonNode) evalString(actual string) (bool, error) {
+	switch n.op {
+	case "==":
+		return actual == n.value, nil
+	case "!=":
+		return actual != n.value, nil
+	case "=~":
+		re, err := regexp.Compile(n.value)
+		if err != nil {
+			return false, fmt.Errorf("invalid regex %q: %s", n.value, err)
+		}
+		return re.MatchString(actual), nil
+	case "!~":
+		re, err := regexp.Compile(n.value)
+		if err != nil {
+			return false, fmt.Errorf("invalid regex %q: %s", n.value, err)
+		}
+		return !re.MatchString(actual), nil
+	default:
+		return false, fmt.Errorf("operator %q is not valid for string column %q", n.op, n.column)
+	}
+}
+
+func (n comparisonNode) evalNumber(actual float64) (bool, error) {
+	var want float64
+	switch n.valueKind {
+	case tokDuration:
+		d, err := time.ParseDuration(n.value)
+		if err != nil {
+			return false, fmt.Errorf("invalid duration %q: %s", n.value, err)
+		}
+		want = d.Seconds()
+	case tokNumber:
+		f, err := strconv.ParseFloat(n.value, 64)
+		if err != nil {
+			return false, fmt.Errorf("invalid number %q: %s", n.value, err)
+		}
+		want = f
+	default:
+		return false, fmt.Errorf("column %q requires a number or duration, got %q", n.column, n.value)
+	}
+
+	switch n.op {
+	case ">":
+		return actual > want, nil
+	case "<":
+		return actual < want, nil
+	case ">=":
+		return actual >= want, nil
+	case "<=":
+		return actual <= want, nil
+	case "==":
+		return actual == want, nil
+	case "!=":
+		return actual != want, nil
+	default:
+		return false, fmt.Errorf("operator %q is not valid for numeric column %q", n.op, n.column)
+	}
+}
+
+// tokenKind enumerates the lexical token types --filter's lexer produces.
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokDuration
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokAnd
+	tokOr
+	tokNot
+)
+
+type filterToken struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// lexFilter tokenizes a --filter expression. pos on every token is the rune
+// offset it started at, so parse errors can point at the offending text.
+func lexFilter(input string) ([]filterToken, error) {
+	var tokens []filterToken
+	runes := []rune(input)
+
+	i := 0
+	for i < len(runes) {
+		c := runes[i]
+
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case c == '(':
+			tokens = append(tokens, filterToken{tokLParen, "(", i})
+			i++
+
+		case c == ')':
+			tokens = append(tokens, filterToken{tokRParen, ")", i})
+			i++
+
+		case c == '\'' || c == '"':
+			quote := c
+			start := i
+			i++
+			var sb strings.Builder
+			for i < len(runes) && runes[i] != quote {
+				sb.WriteRune(runes[i])
+				i++
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string starting at position %d", start)
+			}
+			i++
+			tokens = append(tokens, filterToken{tokString, sb.String(), start})
+
+		case strings.ContainsRune(">=<!~", c):
+			start := i
+			op := string(c)
+			if i+1 < len(runes) && runes[i+1] == '=' && (c == '>' || c == '<' || c == '=' || c == '!') {
+				op += "="
+			} else if (c == '=' || c == '!') && i+1 < len(runes) && runes[i+1] == '~' {
+				op += "~"
+			}
+			switch op {
+			case "=":
+				return nil, fmt.Errorf("unexpected %q at position %d, did you mean \"==\"?", op, start)
+			case "!", "~":
+				return nil, fmt.Errorf("unexpected %q at position %d", op, start)
+			}
+			tokens = append(tokens, filterToken{tokOp, op, start})
+			i += len(op)
+
+		case unicode.IsDigit(c):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			kind := tokNumber
+			if i < len(runes) && unicode.IsLetter(runes[i]) {
+				kind = tokDuration
+				for i < len(runes) && unicode.IsLetter(runes[i]) {
+					i++
+				}
+			}
+			tokens = append(tokens, filterToken{kind, string(runes[start:i]), start})
+
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '-' || runes[i] == '.') {
+				i++
+			}
+			word := string(runes[start:i])
+			switch strings.ToLower(word) {
+			case "and":
+				tokens = append(tokens, filterToken{tokAnd, word, start})
+			case "or":
+				tokens = append(tokens, filterToken{tokOr, word, start})
+			case "not":
+				tokens = append(tokens, filterToken{tokNot, word, start})
+			default:
+				tokens = append(tokens, filterToken{tokIdent, word, start})
+			}
+
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+		}
+	}
+
+	tokens = append(tokens, filterToken{tokEOF, "", len(runes)})
+	return tokens, nil
+}
+
+// filterParser is a tiny recursive-descent parser over the tokens lexFilter
+// produces. Grammar:
+//
+//	expr       := orExpr
+//	orExpr     := andExpr ( "or" andExpr )*
+//	andExpr    := unary ( "and" unary )*
+//	unary      := "not" unary | primary
+//	primary    := "(" expr ")" | comparison
+//	comparison := IDENT op value
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+// parseFilterExpr parses a complete --filter expression string into a
+// filterNode that buildMetaRows' output can be evaluated against.
+func parseFilterExpr(input string) (filterNode, error) {
+	tokens, err := lexFilter(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &filterParser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+
+	return node, nil
+}
+
+func (p *filterParser) peek() filterToken {
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) next() filterToken {
+	tok := p.tokens[p.pos]
+	if p.pos < len(p.tokens)-1 {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokAnd {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (filterNode, error) {
+	if p.peek().kind == tokNot {
+		p.next()
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{inner}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *filterParser) parsePrimary() (filterNode, error) {
+	if p.peek().kind == tokLParen {
+		p.next()
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokRParen {
+			return nil, fmt.Errorf("expected \")\" at position %d, got %q", p.peek().pos, p.peek().text)
+		}
+		p.next()
+		return node, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (filterNode, error) {
+	col := p.next()
+	if col.kind != tokIdent {
+		return nil, fmt.Errorf("expected a column name at position %d, got %q", col.pos, col.text)
+	}
+	if !filterColumns[col.text] {
+		return nil, fmt.Errorf("unknown filter column %q at position %d", col.text, col.pos)
+	}
+
+	op := p.next()
+	if op.kind != tokOp {
+		return nil, fmt.Errorf("expected a comparison operator at position %d, got %q", op.pos, op.text)
+	}
+
+	if filterStringColumns[col.text] {
+		if !filterStringOps[op.text] {
+			return nil, fmt.Errorf("operator %q at position %d is not valid for string column %q", op.text, op.pos, col.text)
+		}
+	} else if !filterNumericOps[op.text] {
+		return nil, fmt.Errorf("operator %q at position %d is not valid for numeric column %q", op.text, op.pos, col.text)
+	}
+
+	val := p.next()
+	switch val.kind {
+	case tokNumber, tokDuration, tokString, tokIdent:
+	default:
+		return nil, fmt.Errorf("expected a value at position %d, got %q", val.pos, val.text)
+	}
+
+	return comparisonNode{column: col.text, op: op.text, value: val.text, valueKind: val.kind}, nil
+}
+
+// filterRows keeps only the rows for which expr evaluates true. A nil expr
+// (no --filter given) is a no-op.
+func filterRows(rows []MetaRow, expr filterNode) ([]MetaRow, error) {
+	if expr == nil {
+		return rows, nil
+	}
+
+	var kept []MetaRow
+	for _, row := range rows {
+		ok, err := expr.eval(row)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			kept = append(kept, row)
+		}
+	}
+
+	return kept, nil
+}
+
+// sortRows orders rows in place by spec, a column name optionally suffixed
+// with ":asc" or ":desc" (default ascending), e.g. "count:desc". An empty
+// spec is a no-op.
+func sortRows(rows []MetaRow, spec string) error {
+	if spec == "" {
+		return nil
+	}
+
+	column := spec
+	desc := false
+	if idx := strings.LastIndex(spec, ":"); idx != -1 {
+		var direction string
+		column, direction = spec[:idx], strings.ToLower(spec[idx+1:])
+		switch direction {
+		case "asc":
+		case "desc":
+			desc = true
+		default:
+			return fmt.Errorf("invalid --sort-by direction %q, expected \"asc\" or \"desc\"", direction)
+		}
+	}
+
+	if !filterColumns[column] {
+		return fmt.Errorf("unknown --sort-by column %q", column)
+	}
+
+	less := func(i, j int) bool {
+		switch column {
+		case "name":
+			return rows[i].Name < rows[j].Name
+		case "source_id":
+			return rows[i].SourceID < rows[j].SourceID
+		case "kind":
+			return rows[i].Kind < rows[j].Kind
+		case "count":
+			return rows[i].Count < rows[j].Count
+		case "expired":
+			return rows[i].Expired < rows[j].Expired
+		case "cache_duration":
+			return rows[i].CacheDurationSeconds < rows[j].CacheDurationSeconds
+		case "rate":
+			var ri, rj int64
+			if rows[i].Rate != nil {
+				ri = *rows[i].Rate
+			}
+			if rows[j].Rate != nil {
+				rj = *rows[j].Rate
+			}
+			return ri < rj
+		default:
+			return false
+		}
+	}
+
+	if desc {
+		sort.SliceStable(rows, func(i, j int) bool { return less(j, i) })
+	} else {
+		sort.SliceStable(rows, less)
+	}
+
+	return nil
+}
+
+// limitRows truncates rows to at most n entries. n <= 0 is a no-op.
+func limitRows(rows []MetaRow, n int) []MetaRow {
+	if n <= 0 || len(rows) <= n {
+		return rows
+	}
+	return rows[:n]
+}
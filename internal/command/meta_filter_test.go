@@ -0,0 +1,91 @@
+package command
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("parseFilterExpr", func() {
+	row := func(count, expired int64, cacheDuration time.Duration, name, kind string) MetaRow {
+		return MetaRow{
+			Name:                 name,
+			Kind:                 kind,
+			Count:                count,
+			Expired:              expired,
+			CacheDurationSeconds: cacheDuration.Seconds(),
+		}
+	}
+
+	DescribeTable("evaluates a parsed expression against a row",
+		func(expr string, r MetaRow, want bool) {
+			node, err := parseFilterExpr(expr)
+			Expect(err).ToNot(HaveOccurred())
+
+			got, err := node.eval(r)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(got).To(Equal(want))
+		},
+		Entry("numeric greater-than", "expired > 10", row(0, 11, 0, "", ""), true),
+		Entry("numeric greater-than, false", "expired > 10", row(0, 5, 0, "", ""), false),
+		Entry("duration less-than", "cache_duration < 1m", row(0, 0, 30*time.Second, "", ""), true),
+		Entry("duration greater-equal, false", "cache_duration >= 1m", row(0, 0, 30*time.Second, "", ""), false),
+		Entry("string equality", `kind == "application"`, row(0, 0, 0, "", "application"), true),
+		Entry("string inequality", "kind != service", row(0, 0, 0, "", "application"), true),
+		Entry("unquoted hyphenated value", "name == my-app", row(0, 0, 0, "my-app", ""), true),
+		Entry("regex match", `name =~ "^my-"`, row(0, 0, 0, "my-app", ""), true),
+		Entry("negated regex match", `name !~ "^my-"`, row(0, 0, 0, "other-app", ""), true),
+		Entry("and", "count > 1 and expired > 1", row(2, 2, 0, "", ""), true),
+		Entry("and short-circuits to false", "count > 100 and expired > 1", row(2, 2, 0, "", ""), false),
+		Entry("or", "count > 100 or expired > 1", row(2, 2, 0, "", ""), true),
+		Entry("not", "not (count > 100)", row(2, 2, 0, "", ""), true),
+		Entry("parens override precedence", "count > 0 and (expired > 100 or expired < 10)", row(2, 2, 0, "", ""), true),
+	)
+
+	DescribeTable("rejects invalid expressions",
+		func(expr string) {
+			_, err := parseFilterExpr(expr)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("unknown column", "bogus > 1"),
+		Entry("unbalanced parens", "(count > 1"),
+		Entry("regex op on numeric column", "count =~ 1"),
+		Entry("comparison op on string column", "name > 1"),
+		Entry("dangling operator", "count >"),
+		Entry("missing operator", "count 1"),
+		Entry("trailing garbage", "count > 1 banana"),
+	)
+})
+
+var _ = Describe("sortRows", func() {
+	It("sorts ascending by default", func() {
+		rows := []MetaRow{{Name: "b", Count: 2}, {Name: "a", Count: 1}}
+		Expect(sortRows(rows, "count")).To(Succeed())
+		Expect(rows[0].Name).To(Equal("a"))
+	})
+
+	It("sorts descending when asked", func() {
+		rows := []MetaRow{{Name: "a", Count: 1}, {Name: "b", Count: 2}}
+		Expect(sortRows(rows, "count:desc")).To(Succeed())
+		Expect(rows[0].Name).To(Equal("b"))
+	})
+
+	It("errors on an unknown column", func() {
+		rows := []MetaRow{{Name: "a"}}
+		Expect(sortRows(rows, "bogus")).To(MatchError(ContainSubstring("unknown --sort-by column")))
+	})
+})
+
+var _ = Describe("limitRows", func() {
+	It("truncates to n rows", func() {
+		rows := []MetaRow{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+		Expect(limitRows(rows, 2)).To(HaveLen(2))
+	})
+
+	It("is a no-op for n <= 0", func() {
+		rows := []MetaRow{{Name: "a"}, {Name: "b"}}
+		Expect(limitRows(rows, 0)).To(HaveLen(2))
+	})
+})
@@ -0,0 +1,82 @@
+package command_test
+
+import (
+	"bytes"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/log-cache-cli/internal/command"
+)
+
+var _ = Describe("Meta Renderers", func() {
+	rate := int64(42)
+	rows := []command.MetaRow{
+		{
+			SourceID:             "source-id-1",
+			Name:                 "my-app",
+			Kind:                 "application",
+			Count:                100,
+			Expired:              10,
+			CacheDurationSeconds: 65,
+			NewestTimestamp:      time.Unix(0, 2000),
+			OldestTimestamp:      time.Unix(0, 1000),
+			Rate:                 &rate,
+		},
+	}
+
+	Describe("TableRenderer", func() {
+		It("renders a human-readable table honoring ShowGUID and EnableNoise", func() {
+			var buf bytes.Buffer
+			r := command.TableRenderer{ShowGUID: true, EnableNoise: true}
+
+			err := r.Render(&buf, rows)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(buf.String()).To(ContainSubstring("Source ID"))
+			Expect(buf.String()).To(ContainSubstring("source-id-1"))
+			Expect(buf.String()).To(ContainSubstring("my-app"))
+			Expect(buf.String()).To(ContainSubstring("Rate"))
+		})
+
+		It("falls back to the source ID when there is no CAPI name", func() {
+			var buf bytes.Buffer
+			r := command.TableRenderer{}
+			unnamed := []command.MetaRow{{SourceID: "source-id-2", Count: 1, Expired: 0}}
+
+			err := r.Render(&buf, unnamed)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(buf.String()).To(ContainSubstring("source-id-2"))
+		})
+	})
+
+	Describe("JSONRenderer", func() {
+		It("emits the documented schema", func() {
+			var buf bytes.Buffer
+			r := command.JSONRenderer{}
+
+			err := r.Render(&buf, rows)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(buf.String()).To(ContainSubstring(`"source_id": "source-id-1"`))
+			Expect(buf.String()).To(ContainSubstring(`"kind": "application"`))
+			Expect(buf.String()).To(ContainSubstring(`"rate": 42`))
+		})
+	})
+
+	Describe("YAMLRenderer", func() {
+		It("emits the documented schema", func() {
+			var buf bytes.Buffer
+			r := command.YAMLRenderer{}
+
+			err := r.Render(&buf, rows)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(buf.String()).To(ContainSubstring("source_id: source-id-1"))
+			Expect(buf.String()).To(ContainSubstring("kind: application"))
+			Expect(buf.String()).To(ContainSubstring("rate: 42"))
+		})
+	})
+})
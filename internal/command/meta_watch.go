@@ -0,0 +1,198 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"code.cloudfoundry.org/cli/plugin"
+	logcache "code.cloudfoundry.org/go-log-cache"
+	"golang.org/x/term"
+)
+
+// hideCursor/showCursor are the standard ANSI escapes for cursor control;
+// clearScreen additionally homes the cursor so each refresh redraws from the
+// top of the terminal, like top(1).
+const (
+	hideCursor  = "\x1b[?25l"
+	showCursor  = "\x1b[?25h"
+	clearScreen = "\x1b[H\x1b[2J"
+)
+
+// sourceSnapshot is the previous refresh's counters for a single source,
+// kept so watchMeta can report Δcount/Δexpired and a smoothed ingress rate.
+type sourceSnapshot struct {
+	count           int64
+	expired         int64
+	newestTimestamp int64
+}
+
+// watchMeta re-queries client.Meta on a ticker and redraws the table in
+// place, the way top(1) does. It replaces the per-refresh tailer() calls
+// --noise would otherwise trigger: the ingress rate is derived instead from
+// the count/timestamp deltas between polls.
+func watchMeta(
+	ctx context.Context,
+	client *logcache.Client,
+	cli plugin.CliConnection,
+	tailer Tailer,
+	log Logger,
+	tableWriter io.Writer,
+	opts optionsFlags,
+	scope string,
+	output string,
+	username string,
+	interval time.Duration,
+	cache *nameCache,
+	timeout time.Duration,
+	filterExpr filterNode,
+) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	defer signal.Stop(sigCh)
+
+	isTTY := isTerminal(tableWriter)
+	if isTTY {
+		fmt.Fprint(tableWriter, hideCursor)
+		defer fmt.Fprint(tableWriter, showCursor)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	snapshots := map[string]sourceSnapshot{}
+
+	for {
+		fetchCtx, cancelFetch := context.WithTimeout(ctx, timeout)
+		rows, partial, err := fetchMetaRows(fetchCtx, client, cli, scope, false, nil, cache)
+		cancelFetch()
+		if err != nil {
+			log.Printf("Failed to refresh Meta information: %s", err)
+		} else {
+			if cache != nil {
+				if err := cache.save(); err != nil {
+					log.Printf("Could not persist name cache: %s", err)
+				}
+			}
+
+			applyDeltas(rows, snapshots)
+
+			rows, err = filterRows(rows, filterExpr)
+			if err != nil {
+				log.Fatalf("Could not apply --filter: %s", err)
+			}
+			if err := sortRows(rows, opts.SortBy); err != nil {
+				log.Fatalf("Could not apply --sort-by: %s", err)
+			}
+			rows = limitRows(rows, opts.Limit)
+
+			if isTTY {
+				fmt.Fprint(tableWriter, clearScreen)
+				fmt.Fprintf(tableWriter, "Watching log cache metadata as %s (refresh every %s, ctrl-c to exit)...\n\n", username, interval)
+			}
+
+			renderer := rendererFor(output, opts)
+			if tr, ok := renderer.(TableRenderer); ok {
+				tr.ShowDeltas = true
+				tr.EnableNoise = false
+				renderer = tr
+			}
+			if err := renderer.Render(tableWriter, rows); err != nil {
+				log.Fatalf("Failed to render Meta information: %s", err)
+			}
+
+			if partial {
+				fmt.Fprintln(tableWriter, "\n(partial: deadline exceeded)")
+			}
+		}
+
+		select {
+		case <-sigCh:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// fetchMetaRows performs a single client.Meta + CAPI enrichment round trip,
+// producing the same MetaRow shape the non-watch path renders. enableNoise
+// and tailer are passed on to buildMetaRows; watchMeta passes false and nil,
+// since watch mode derives its own rate from deltas, while MetaExporter
+// passes its --noise setting. The returned bool reports whether ctx's
+// deadline cut the CAPI enrichment short.
+func fetchMetaRows(ctx context.Context, client *logcache.Client, cli plugin.CliConnection, scope string, enableNoise bool, tailer Tailer, cache *nameCache) ([]MetaRow, bool, error) {
+	meta, err := client.Meta(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	resources, partial, err := getSourceInfo(ctx, meta, cli, cache)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return buildMetaRows(meta, resources, scope, enableNoise, tailer), partial, nil
+}
+
+// applyDeltas fills in DeltaCount/DeltaExpired/Rate on each row by comparing
+// against the previous poll's snapshot, then updates the snapshot in place
+// for the next refresh. Sources seen for the first time get no delta. Rate
+// is derived from the NewestTimestamp delta rather than wall-clock time
+// between polls, so it stays accurate even when the watch ticker is delayed
+// (slow CAPI enrichment, GC pause, etc).
+func applyDeltas(rows []MetaRow, snapshots map[string]sourceSnapshot) {
+	seen := make(map[string]bool, len(rows))
+
+	for i := range rows {
+		row := &rows[i]
+		seen[row.SourceID] = true
+
+		prev, ok := snapshots[row.SourceID]
+		snapshots[row.SourceID] = sourceSnapshot{
+			count:           row.Count,
+			expired:         row.Expired,
+			newestTimestamp: row.NewestTimestamp.UnixNano(),
+		}
+
+		if !ok {
+			continue
+		}
+
+		deltaCount := row.Count - prev.count
+		deltaExpired := row.Expired - prev.expired
+		row.DeltaCount = &deltaCount
+		row.DeltaExpired = &deltaExpired
+
+		elapsed := time.Duration(row.NewestTimestamp.UnixNano() - prev.newestTimestamp).Seconds()
+		if elapsed > 0 {
+			rate := int64(float64(deltaCount) / elapsed)
+			row.Rate = &rate
+		}
+	}
+
+	for id := range snapshots {
+		if !seen[id] {
+			delete(snapshots, id)
+		}
+	}
+}
+
+// isTerminal reports whether w is an interactive TTY. Non-*os.File writers
+// (buffers, pipes, files) fall back to periodic full prints with no cursor
+// control, since clearing the screen would just emit garbage escapes into a
+// log or pipe.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	return term.IsTerminal(int(f.Fd()))
+}
@@ -0,0 +1,47 @@
+package command
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("applyDeltas", func() {
+	It("leaves deltas nil the first time a source is seen", func() {
+		rows := []MetaRow{{SourceID: "a", Count: 10, Expired: 1}}
+		snapshots := map[string]sourceSnapshot{}
+
+		applyDeltas(rows, snapshots)
+
+		Expect(rows[0].DeltaCount).To(BeNil())
+		Expect(rows[0].DeltaExpired).To(BeNil())
+	})
+
+	It("reports the change since the previous snapshot", func() {
+		newest := time.Now()
+		rows := []MetaRow{{SourceID: "a", Count: 10, Expired: 1, NewestTimestamp: newest}}
+		snapshots := map[string]sourceSnapshot{
+			"a": {count: 4, expired: 1, newestTimestamp: newest.Add(-2 * time.Second).UnixNano()},
+		}
+
+		applyDeltas(rows, snapshots)
+
+		Expect(*rows[0].DeltaCount).To(Equal(int64(6)))
+		Expect(*rows[0].DeltaExpired).To(Equal(int64(0)))
+		Expect(*rows[0].Rate).To(Equal(int64(3)))
+	})
+
+	It("drops snapshots for sources that disappeared", func() {
+		rows := []MetaRow{{SourceID: "a", Count: 1}}
+		snapshots := map[string]sourceSnapshot{
+			"a": {count: 1},
+			"b": {count: 1},
+		}
+
+		applyDeltas(rows, snapshots)
+
+		Expect(snapshots).To(HaveKey("a"))
+		Expect(snapshots).ToNot(HaveKey("b"))
+	})
+})
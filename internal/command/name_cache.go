@@ -0,0 +1,160 @@
+package command
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// nameCacheEntry is one cached CAPI GUID -> name/kind lookup.
+type nameCacheEntry struct {
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// nameCacheFile is the on-disk schema, keyed first by API endpoint (the same
+// GUID on two foundations can resolve to different apps) and then by source
+// GUID.
+type nameCacheFile struct {
+	Entries map[string]map[string]nameCacheEntry `json:"entries"`
+}
+
+// nameCache is a small TTL'd cache of CAPI GUID->name/kind lookups, backed by
+// a JSON file so repeated `log-cache meta` runs against the same foundation
+// don't re-hit /v3/apps and /v2/service_instances for sources whose names
+// haven't changed.
+type nameCache struct {
+	mu          sync.Mutex
+	path        string
+	ttl         time.Duration
+	apiEndpoint string
+	entries     map[string]nameCacheEntry
+	dirty       bool
+}
+
+// defaultNameCachePath returns $XDG_CACHE_HOME/log-cache-cli/names.json,
+// falling back to os.UserCacheDir() when XDG_CACHE_HOME is unset.
+func defaultNameCachePath() (string, error) {
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		return filepath.Join(dir, "log-cache-cli", "names.json"), nil
+	}
+
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "log-cache-cli", "names.json"), nil
+}
+
+// loadNameCache reads path, if it exists, and scopes the returned cache to
+// apiEndpoint. A missing file is not an error; the cache just starts empty.
+func loadNameCache(path, apiEndpoint string, ttl time.Duration) (*nameCache, error) {
+	c := &nameCache{
+		path:        path,
+		ttl:         ttl,
+		apiEndpoint: apiEndpoint,
+		entries:     map[string]nameCacheEntry{},
+	}
+
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var f nameCacheFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, err
+	}
+
+	if entries, ok := f.Entries[apiEndpoint]; ok {
+		c.entries = entries
+	}
+
+	return c, nil
+}
+
+// lookup returns the cached entry for guid, provided it exists and hasn't
+// aged past the cache's TTL.
+func (c *nameCache) lookup(guid string) (nameCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[guid]
+	if !ok || time.Since(e.FetchedAt) > c.ttl {
+		return nameCacheEntry{}, false
+	}
+
+	return e, true
+}
+
+// put records a fresh lookup result for guid.
+func (c *nameCache) put(guid, name, kind string, fetchedAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[guid] = nameCacheEntry{Name: name, Kind: kind, FetchedAt: fetchedAt}
+	c.dirty = true
+}
+
+// invalidate drops guid from the cache, e.g. because CAPI just reported it
+// no longer exists.
+func (c *nameCache) invalidate(guid string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[guid]; ok {
+		delete(c.entries, guid)
+		c.dirty = true
+	}
+}
+
+// save persists the cache to disk if anything changed since it was loaded,
+// merging with whatever is currently on disk for other API endpoints so
+// concurrent invocations against different foundations don't clobber one
+// another.
+func (c *nameCache) save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.dirty {
+		return nil
+	}
+
+	f := nameCacheFile{Entries: map[string]map[string]nameCacheEntry{}}
+	if b, err := os.ReadFile(c.path); err == nil {
+		_ = json.Unmarshal(b, &f)
+	}
+	if f.Entries == nil {
+		f.Entries = map[string]map[string]nameCacheEntry{}
+	}
+
+	f.Entries[c.apiEndpoint] = c.entries
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, c.path); err != nil {
+		return err
+	}
+
+	c.dirty = false
+	return nil
+}
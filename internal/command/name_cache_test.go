@@ -0,0 +1,110 @@
+package command
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/cli/plugin/pluginfakes"
+	"code.cloudfoundry.org/go-log-cache/rpc/logcache_v1"
+)
+
+var _ = Describe("name cache", func() {
+	var (
+		tmpDir      string
+		cachePath   string
+		apiEndpoint string
+		fakeCli     *pluginfakes.FakeCliConnection
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "log-cache-cli-name-cache")
+		Expect(err).ToNot(HaveOccurred())
+
+		cachePath = filepath.Join(tmpDir, "names.json")
+		apiEndpoint = "https://api.example.com"
+
+		fakeCli = new(pluginfakes.FakeCliConnection)
+		fakeCli.ApiEndpointReturns(apiEndpoint, nil)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	It("only curls CAPI for cache misses, and persists the merged result", func() {
+		fakeCli.CliCommandWithoutTerminalOutputReturns(
+			[]string{`{"resources":[{"guid":"app-1","name":"my-app"}]}`},
+			nil,
+		)
+
+		meta := map[string]*logcache_v1.MetaInfo{"app-1": {Count: 10, Expired: 1}}
+
+		cold, err := loadNameCache(cachePath, apiEndpoint, time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+
+		resources, partial, err := getSourceInfo(context.Background(), meta, fakeCli, cold)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(partial).To(BeFalse())
+		Expect(resources).To(ConsistOf(source{GUID: "app-1", Name: "my-app", Kind: "application"}))
+		Expect(fakeCli.CliCommandWithoutTerminalOutputCallCount()).To(Equal(1))
+
+		Expect(cold.save()).To(Succeed())
+
+		warm, err := loadNameCache(cachePath, apiEndpoint, time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+
+		resources, _, err = getSourceInfo(context.Background(), meta, fakeCli, warm)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resources).To(ConsistOf(source{GUID: "app-1", Name: "my-app", Kind: "application"}))
+		Expect(fakeCli.CliCommandWithoutTerminalOutputCallCount()).To(Equal(1), "a warm cache should not issue any new CAPI curls")
+	})
+
+	It("expires entries past the TTL and re-fetches them", func() {
+		fakeCli.CliCommandWithoutTerminalOutputReturns(
+			[]string{`{"resources":[{"guid":"app-1","name":"my-app"}]}`},
+			nil,
+		)
+		meta := map[string]*logcache_v1.MetaInfo{"app-1": {Count: 10, Expired: 1}}
+
+		shortTTL, err := loadNameCache(cachePath, apiEndpoint, time.Nanosecond)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, _, err = getSourceInfo(context.Background(), meta, fakeCli, shortTTL)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(shortTTL.save()).To(Succeed())
+
+		time.Sleep(time.Millisecond)
+
+		reloaded, err := loadNameCache(cachePath, apiEndpoint, time.Nanosecond)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, _, err = getSourceInfo(context.Background(), meta, fakeCli, reloaded)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fakeCli.CliCommandWithoutTerminalOutputCallCount()).To(Equal(2))
+	})
+
+	It("drops an entry when CAPI no longer returns it for a stale guid", func() {
+		fakeCli.CliCommandWithoutTerminalOutputReturns(
+			[]string{`{"resources":[]}`}, nil,
+		)
+
+		cache, err := loadNameCache(cachePath, apiEndpoint, time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+		cache.put("app-1", "my-app", "application", time.Now().Add(-2*time.Hour))
+
+		meta := map[string]*logcache_v1.MetaInfo{"app-1": {Count: 10, Expired: 1}}
+
+		_, _, err = getSourceInfo(context.Background(), meta, fakeCli, cache)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, ok := cache.lookup("app-1")
+		Expect(ok).To(BeFalse())
+	})
+})
@@ -0,0 +1,722 @@
+// Package k8s provides Log Cache CLI subcommands meant to be wired into a
+// kubectl plugin, as opposed to the internal/command package's cf CLI
+// plugin commands.
+package k8s
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Config holds the connection details NewMeta (and the rest of this
+// package's commands) need to reach a Log Cache instance, plus the logger
+// they report diagnostics to. A nil Logger is replaced with one built from
+// --log-format/--log-level (or discarded entirely if neither flag was set),
+// so programmatic callers that don't care about CLI diagnostics can leave
+// it unset.
+type Config struct {
+	Addr   string
+	Logger *slog.Logger
+}
+
+// lastEventIDHeader and eventIDHeader implement Last-Event-ID semantics
+// modeled on the SSE spec: the client echoes back the last ID it saw via
+// lastEventIDHeader, and the server advertises the ID of the batch it just
+// served via eventIDHeader, so a reconnect only receives what's new.
+const (
+	lastEventIDHeader = "Last-Event-ID"
+	eventIDHeader     = "X-Log-Cache-Event-Id"
+)
+
+type metaOptions struct {
+	noHeaders     bool
+	timeout       time.Duration
+	watchInterval time.Duration
+	format        string
+	namespaces    []string
+	resourceType  string
+	selector      Selector
+	parentCtx     context.Context
+	maxAttempts   int
+	baseBackoff   time.Duration
+}
+
+// baseContext is the context runMeta/runMetaWatch build their per-request
+// deadlines from: the context WithMetaContext supplied, falling back to
+// cmd's own context (set by cobra's ExecuteContext), falling back to
+// context.Background().
+func (o metaOptions) baseContext(cmd *cobra.Command) context.Context {
+	if o.parentCtx != nil {
+		return o.parentCtx
+	}
+	if ctx := cmd.Context(); ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
+
+// MetaOption configures NewMeta.
+type MetaOption func(*metaOptions)
+
+// WithMetaNoHeaders suppresses the table header row, e.g. when piping meta's
+// output into another command.
+func WithMetaNoHeaders() MetaOption {
+	return func(o *metaOptions) {
+		o.noHeaders = true
+	}
+}
+
+// WithMetaTimeout bounds how long a single fetch from Log Cache may take,
+// including every attempt and backoff sleep WithMetaRetry adds to it.
+func WithMetaTimeout(d time.Duration) MetaOption {
+	return func(o *metaOptions) {
+		o.timeout = d
+	}
+}
+
+// WithMetaWatchInterval sets how often --watch polls Log Cache when the
+// server doesn't advertise push support, i.e. it never echoes eventIDHeader.
+// It has no effect outside --watch.
+func WithMetaWatchInterval(d time.Duration) MetaOption {
+	return func(o *metaOptions) {
+		o.watchInterval = d
+	}
+}
+
+// WithMetaFormat sets the default output format: "table" (the default),
+// "json", "yaml", or "csv". The --output/-o flag overrides this at runtime.
+func WithMetaFormat(format string) MetaOption {
+	return func(o *metaOptions) {
+		o.format = format
+	}
+}
+
+// WithMetaNamespaces restricts rows to sources whose sourceID resolved to
+// one of the given namespaces. The --namespace/-n flag appends to this set
+// rather than replacing it.
+func WithMetaNamespaces(namespaces ...string) MetaOption {
+	return func(o *metaOptions) {
+		o.namespaces = append(o.namespaces, namespaces...)
+	}
+}
+
+// WithMetaResourceType restricts rows to sources of the given resource type
+// (e.g. "pod", "deployment"). The --resource-type flag overrides this.
+func WithMetaResourceType(resourceType string) MetaOption {
+	return func(o *metaOptions) {
+		o.resourceType = resourceType
+	}
+}
+
+// WithMetaSelector restricts rows to sources matching sel, a predicate
+// already parsed via ParseSelector. Programmatic callers that build a
+// Selector directly should use this instead of ParseSelector-ing a string
+// built for the --selector/-l flag. The --selector flag is ANDed with this
+// on top, rather than replacing it.
+func WithMetaSelector(sel Selector) MetaOption {
+	return func(o *metaOptions) {
+		o.selector.requirements = append(o.selector.requirements, sel.requirements...)
+	}
+}
+
+// WithMetaContext supplies the parent context request deadlines are derived
+// from, so a caller embedding this command in a larger, signal-handling CLI
+// can cancel it cleanly. It takes precedence over a context set via cobra's
+// ExecuteContext.
+func WithMetaContext(ctx context.Context) MetaOption {
+	return func(o *metaOptions) {
+		o.parentCtx = ctx
+	}
+}
+
+// WithMetaRetry enables jittered exponential backoff retries of up to
+// maxAttempts total attempts, starting at baseBackoff, whenever a request
+// fails with a 5xx status or a connection-level error. Retries stop
+// immediately, without sleeping out a pending backoff, if the governing
+// context is cancelled or its deadline expires. The default, with no
+// WithMetaRetry option, is a single attempt with no retry, matching
+// behavior before this option existed.
+func WithMetaRetry(maxAttempts int, baseBackoff time.Duration) MetaOption {
+	return func(o *metaOptions) {
+		o.maxAttempts = maxAttempts
+		o.baseBackoff = baseBackoff
+	}
+}
+
+// NewMeta builds the `meta` subcommand, which prints Log Cache's per-source
+// envelope counts the way `kubectl get` prints resources: one row per
+// source, grouped and sorted by namespace/name/resource once a sourceID
+// resolves to a Kubernetes object (namespace/resource/name), with sources
+// that don't parse that way listed first, sorted alphabetically by ID.
+// --namespace, --resource-type, and --selector narrow which rows print,
+// the same way `kubectl get -n ... -l ...` does.
+func NewMeta(cfg Config, opts ...MetaOption) *cobra.Command {
+	o := metaOptions{
+		timeout:       5 * time.Second,
+		watchInterval: 2 * time.Second,
+		format:        "table",
+		maxAttempts:   1,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.format == "" {
+		o.format = "table"
+	}
+
+	var watch bool
+	format := o.format
+	var namespaces []string
+	resourceType := o.resourceType
+	var selectorExpr string
+	var logFormat string
+	var logLevel string
+
+	cmd := &cobra.Command{
+		Use:   "meta",
+		Short: "Shows the envelope counts cached per source",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if invalidMetaFormat(format) {
+				return fmt.Errorf("invalid --output %q: must be table, json, yaml, or csv", format)
+			}
+
+			o.namespaces = append(o.namespaces, namespaces...)
+			o.resourceType = resourceType
+
+			if selectorExpr != "" {
+				sel, err := ParseSelector(selectorExpr)
+				if err != nil {
+					return fmt.Errorf("invalid --selector: %s", err)
+				}
+				o.selector.requirements = append(o.selector.requirements, sel.requirements...)
+			}
+
+			logger, err := resolveLogger(cmd, cfg, logFormat, logLevel)
+			if err != nil {
+				return err
+			}
+
+			if watch {
+				return runMetaWatch(cmd, cfg, o, format, logger)
+			}
+			return runMeta(cmd, cfg, o, format, logger)
+		},
+	}
+	cmd.Flags().BoolVarP(&watch, "watch", "w", false, "keep the connection open and print rows as source metadata changes")
+	cmd.Flags().StringVarP(&format, "output", "o", format, "output format: table, json, yaml, or csv")
+	cmd.Flags().StringArrayVarP(&namespaces, "namespace", "n", nil, "only show sources in this namespace (repeatable)")
+	cmd.Flags().StringVar(&resourceType, "resource-type", resourceType, "only show sources of this resource type, e.g. pod, deployment")
+	cmd.Flags().StringVarP(&selectorExpr, "selector", "l", "", "only show sources matching this selector, e.g. \"namespace=foo,type in (pod,deployment)\"")
+	cmd.Flags().StringVar(&logFormat, "log-format", "text", "log output format when logging is enabled: text or json")
+	cmd.Flags().StringVar(&logLevel, "log-level", "info", "log level when logging is enabled: debug, info, warn, or error")
+
+	return cmd
+}
+
+// resolveLogger picks the *slog.Logger RunE should use: cfg.Logger, if a
+// programmatic caller set one; otherwise one built from --log-format/
+// --log-level if the operator explicitly set either; otherwise a discard
+// logger, preserving this command's historical silence.
+func resolveLogger(cmd *cobra.Command, cfg Config, logFormat, logLevel string) (*slog.Logger, error) {
+	if cfg.Logger != nil {
+		return cfg.Logger, nil
+	}
+	if !cmd.Flags().Changed("log-format") && !cmd.Flags().Changed("log-level") {
+		return discardLogger(), nil
+	}
+
+	level, err := parseLogLevel(logLevel)
+	if err != nil {
+		return nil, err
+	}
+	handler, err := newLogHandler(logFormat, level, cmd.ErrOrStderr())
+	if err != nil {
+		return nil, err
+	}
+	return slog.New(handler), nil
+}
+
+func invalidMetaFormat(format string) bool {
+	switch format {
+	case "table", "json", "yaml", "csv":
+		return false
+	default:
+		return true
+	}
+}
+
+func runMeta(cmd *cobra.Command, cfg Config, o metaOptions, format string, logger *slog.Logger) error {
+	ctx, cancel := context.WithTimeout(o.baseContext(cmd), o.timeout)
+	defer cancel()
+
+	start := time.Now()
+	logger.Info("meta.fetch", "addr", cfg.Addr)
+
+	sources, _, _, err := fetchMetaWithRetry(ctx, cfg.Addr, "", o.maxAttempts, o.baseBackoff, logger)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			logger.Warn("meta.cancelled")
+		} else if errors.Is(err, context.DeadlineExceeded) {
+			logger.Warn("meta.timeout", "timeout", o.timeout)
+		}
+		return err
+	}
+
+	filtered := filterMetaSources(sources, o)
+	logger.Info("meta.fetch.complete", "addr", cfg.Addr, "sources", len(filtered), "elapsed", time.Since(start))
+
+	return renderMeta(cmd.OutOrStdout(), filtered, o.noHeaders, format)
+}
+
+// filterMetaSources keeps only sources matching all of o's namespace,
+// resource-type, and selector constraints. An empty o matches everything,
+// preserving current behavior for callers that set none of them.
+func filterMetaSources(sources []metaSource, o metaOptions) []metaSource {
+	if len(o.namespaces) == 0 && o.resourceType == "" && len(o.selector.requirements) == 0 {
+		return sources
+	}
+
+	filtered := make([]metaSource, 0, len(sources))
+	for _, s := range sources {
+		if len(o.namespaces) > 0 && !containsString(o.namespaces, s.namespace) {
+			continue
+		}
+		if o.resourceType != "" && s.resource != o.resourceType {
+			continue
+		}
+		if !o.selector.Matches(map[string]string{
+			"namespace": s.namespace,
+			"type":      s.resource,
+			"name":      s.name,
+		}) {
+			continue
+		}
+		filtered = append(filtered, s)
+	}
+	return filtered
+}
+
+func containsString(values []string, v string) bool {
+	for _, c := range values {
+		if c == v {
+			return true
+		}
+	}
+	return false
+}
+
+// runMetaWatch polls fetchMeta, threading the event ID it's given back on
+// the next request, and prints only rows that are new or differ in any field
+// (count, expired, cache duration, or timestamps) from the last time they
+// were printed.
+// Output is flushed after every row, since a piped consumer (e.g. `watch` or
+// a log aggregator) should see each row as it arrives rather than waiting on
+// an internal buffer.
+func runMetaWatch(cmd *cobra.Command, cfg Config, o metaOptions, format string, logger *slog.Logger) error {
+	out := cmd.OutOrStdout()
+	ctx := o.baseContext(cmd)
+
+	var csvWriter *csv.Writer
+	if format == "csv" {
+		csvWriter = csv.NewWriter(out)
+		if !o.noHeaders {
+			if err := csvWriter.Write(metaCSVHeader); err != nil {
+				return err
+			}
+			csvWriter.Flush()
+			flushWriter(out)
+		}
+	}
+
+	logger.Info("meta.watch.start", "addr", cfg.Addr, "interval", o.watchInterval)
+
+	var lastEventID string
+	seen := map[string]metaSource{}
+
+	for {
+		reqCtx, cancel := context.WithTimeout(ctx, o.timeout)
+		sources, eventID, reset, err := fetchMetaWithRetry(reqCtx, cfg.Addr, lastEventID, o.maxAttempts, o.baseBackoff, logger)
+		cancel()
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				logger.Warn("meta.cancelled")
+			} else if errors.Is(err, context.DeadlineExceeded) {
+				logger.Warn("meta.timeout", "timeout", o.timeout)
+			}
+			return err
+		}
+		lastEventID = eventID
+
+		if reset {
+			// The server no longer recognizes lastEventID (e.g. its buffer
+			// rotated past it). Rather than guess at what was missed, start
+			// over from the current tail and say so, so a watching operator
+			// knows the stream isn't contiguous.
+			logger.Warn("meta.reset", "addr", cfg.Addr)
+			fmt.Fprintln(out, "--- reset: resuming from current tail ---")
+			flushWriter(out)
+			seen = map[string]metaSource{}
+		}
+
+		sources = filterMetaSources(sources, o)
+
+		sortMetaSources(sources)
+		for _, s := range sources {
+			if prev, ok := seen[s.sourceID]; ok && prev == s {
+				continue
+			}
+			seen[s.sourceID] = s
+			if err := writeMetaRowFormatted(out, s, format, csvWriter); err != nil {
+				return err
+			}
+			flushWriter(out)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(o.watchInterval):
+		}
+	}
+}
+
+// flushWriter flushes w if it supports buffering, so each watch row reaches
+// a piped consumer immediately instead of sitting in an internal buffer.
+func flushWriter(w io.Writer) {
+	if f, ok := w.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+		return
+	}
+	if f, ok := w.(interface{ Flush() }); ok {
+		f.Flush()
+	}
+}
+
+// metaResponse is the JSON shape Log Cache's /v1/meta endpoint returns;
+// count/expired/the timestamps come back as strings because that's how
+// Log Cache's gateway marshals the underlying int64 proto fields.
+type metaResponse struct {
+	Meta map[string]struct {
+		Count           string `json:"count"`
+		Expired         string `json:"expired"`
+		OldestTimestamp string `json:"oldestTimestamp"`
+		NewestTimestamp string `json:"newestTimestamp"`
+	} `json:"meta"`
+}
+
+// metaSource is one row of Meta's output. grouped is true when sourceID
+// parsed as "namespace/resource/name", in which case namespace/resource/name
+// are populated and render in place of the raw ID.
+type metaSource struct {
+	sourceID      string
+	grouped       bool
+	namespace     string
+	resource      string
+	name          string
+	count         int64
+	expired       int64
+	oldest        time.Time
+	newest        time.Time
+	cacheDuration time.Duration
+}
+
+func (s metaSource) resourceColumn() string {
+	if s.grouped {
+		return s.name
+	}
+	return s.sourceID
+}
+
+func (s metaSource) typeColumn() string {
+	if s.grouped {
+		return s.resource
+	}
+	return "-"
+}
+
+func (s metaSource) namespaceColumn() string {
+	if s.grouped {
+		return s.namespace
+	}
+	return "-"
+}
+
+// fetchMeta performs a single request to addr's /v1/meta endpoint, sending
+// lastEventID (if any) so the server can reply with only what's changed
+// since then. If the server responds 410 Gone, meaning lastEventID is no
+// longer recognized, fetchMeta restarts the request from the current tail
+// and reports reset=true rather than surfacing that as an error.
+func fetchMeta(ctx context.Context, addr, lastEventID string) (sources []metaSource, eventID string, reset bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, addr+"/v1/meta", nil)
+	if err != nil {
+		return nil, "", false, err
+	}
+	if lastEventID != "" {
+		req.Header.Set(lastEventIDHeader, lastEventID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, "", false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusGone {
+		sources, eventID, _, err = fetchMeta(ctx, addr, "")
+		return sources, eventID, true, err
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, "", false, &metaServerError{status: resp.StatusCode}
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, "", false, fmt.Errorf("log cache meta request failed with status %d", resp.StatusCode)
+	}
+
+	var body metaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil && err != io.EOF {
+		return nil, "", false, err
+	}
+
+	sources = make([]metaSource, 0, len(body.Meta))
+	for sourceID, m := range body.Meta {
+		sources = append(sources, newMetaSource(sourceID, m.Count, m.Expired, m.OldestTimestamp, m.NewestTimestamp))
+	}
+
+	return sources, resp.Header.Get(eventIDHeader), false, nil
+}
+
+func newMetaSource(sourceID, countStr, expiredStr, oldestStr, newestStr string) metaSource {
+	oldestNanos, newestNanos := parseInt64(oldestStr), parseInt64(newestStr)
+
+	s := metaSource{
+		sourceID:      sourceID,
+		count:         parseInt64(countStr),
+		expired:       parseInt64(expiredStr),
+		oldest:        time.Unix(0, oldestNanos).UTC(),
+		newest:        time.Unix(0, newestNanos).UTC(),
+		cacheDuration: cacheDuration(oldestNanos, newestNanos),
+	}
+
+	if parts := strings.SplitN(sourceID, "/", 3); len(parts) == 3 && parts[0] != "" && parts[1] != "" && parts[2] != "" {
+		s.grouped = true
+		s.namespace, s.resource, s.name = parts[0], parts[1], parts[2]
+	}
+
+	return s
+}
+
+func parseInt64(s string) int64 {
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
+
+// cacheDuration reports how long the cache currently spans, truncated to
+// the second. A source with less than a second of retention (or a brand new
+// source where oldest == newest) still shows 1s rather than 0s, since 0s
+// reads as "nothing cached" when something plainly is.
+func cacheDuration(oldestNanos, newestNanos int64) time.Duration {
+	d := time.Duration(newestNanos - oldestNanos).Truncate(time.Second)
+	if d < time.Second {
+		return time.Second
+	}
+	return d
+}
+
+// sortMetaSources orders ungrouped sources first (alphabetically by ID),
+// then grouped sources by namespace, name, and resource type.
+func sortMetaSources(sources []metaSource) {
+	sort.Slice(sources, func(i, j int) bool {
+		a, b := sources[i], sources[j]
+
+		if a.grouped != b.grouped {
+			return !a.grouped
+		}
+		if !a.grouped {
+			return a.sourceID < b.sourceID
+		}
+		if a.namespace != b.namespace {
+			return a.namespace < b.namespace
+		}
+		if a.name != b.name {
+			return a.name < b.name
+		}
+		return a.resource < b.resource
+	})
+}
+
+// renderMeta writes sources in format ("table", "json", "yaml", or "csv"),
+// in sorted order. A zero-source table prints nothing at all, avoiding a
+// misleading header-only table; json/yaml/csv instead emit a valid empty
+// document (`[]`, an empty YAML list, or just the header row), since a
+// script piping into jq/yq expects that rather than empty stdin.
+func renderMeta(w io.Writer, sources []metaSource, noHeaders bool, format string) error {
+	sortMetaSources(sources)
+
+	switch format {
+	case "json":
+		return renderMetaJSON(w, sources)
+	case "yaml":
+		return renderMetaYAML(w, sources)
+	case "csv":
+		return renderMetaCSV(w, sources, noHeaders)
+	default:
+		// Printing nothing at all (not even a header) when there are no
+		// sources avoids a misleading header-only table; json/yaml/csv still
+		// emit a valid, empty document so a piped jq/yq/csv reader doesn't
+		// choke on empty stdin.
+		if len(sources) == 0 {
+			return nil
+		}
+		renderMetaTable(w, sources, noHeaders)
+		return nil
+	}
+}
+
+// renderMetaTable writes sources as an aligned table, the way `kubectl get`
+// does.
+func renderMetaTable(w io.Writer, sources []metaSource, noHeaders bool) {
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	if !noHeaders {
+		fmt.Fprintln(tw, "RESOURCE\tTYPE\tNAMESPACE\tCOUNT\tEXPIRED\tCACHE DURATION")
+	}
+	for _, s := range sources {
+		writeMetaRow(tw, s)
+	}
+	tw.Flush()
+}
+
+// writeMetaRow writes a single source's row. renderMetaTable passes it a
+// *tabwriter.Writer so a full table's columns align; runMetaWatch passes it
+// the raw output writer so each row, once flushed, reaches a piped consumer
+// immediately rather than waiting on a redraw of the whole table.
+func writeMetaRow(w io.Writer, s metaSource) {
+	fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%s\n",
+		s.resourceColumn(), s.typeColumn(), s.namespaceColumn(), s.count, s.expired, s.cacheDuration)
+}
+
+// metaOutputRow is the schema JSON, YAML, and CSV output share, exposing the
+// fields meta resolved from each sourceID so scripts can pipe into jq/yq
+// instead of scraping the table.
+type metaOutputRow struct {
+	Resource        string    `json:"resource" yaml:"resource"`
+	Type            string    `json:"type" yaml:"type"`
+	Namespace       string    `json:"namespace" yaml:"namespace"`
+	Count           int64     `json:"count" yaml:"count"`
+	Expired         int64     `json:"expired" yaml:"expired"`
+	CacheDuration   string    `json:"cache_duration" yaml:"cache_duration"`
+	OldestTimestamp time.Time `json:"oldest_timestamp" yaml:"oldest_timestamp"`
+	NewestTimestamp time.Time `json:"newest_timestamp" yaml:"newest_timestamp"`
+}
+
+func toOutputRow(s metaSource) metaOutputRow {
+	return metaOutputRow{
+		Resource:        s.resourceColumn(),
+		Type:            s.typeColumn(),
+		Namespace:       s.namespaceColumn(),
+		Count:           s.count,
+		Expired:         s.expired,
+		CacheDuration:   s.cacheDuration.String(),
+		// Truncated to the second so JSON and YAML print the same RFC3339
+		// value CSV does.
+		OldestTimestamp: s.oldest.Truncate(time.Second),
+		NewestTimestamp: s.newest.Truncate(time.Second),
+	}
+}
+
+func renderMetaJSON(w io.Writer, sources []metaSource) error {
+	rows := make([]metaOutputRow, len(sources))
+	for i, s := range sources {
+		rows[i] = toOutputRow(s)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rows)
+}
+
+func renderMetaYAML(w io.Writer, sources []metaSource) error {
+	rows := make([]metaOutputRow, len(sources))
+	for i, s := range sources {
+		rows[i] = toOutputRow(s)
+	}
+
+	return yaml.NewEncoder(w).Encode(rows)
+}
+
+// metaCSVHeader is shared by the one-shot CSV render and --watch's streaming
+// CSV output, so both always agree on column order.
+var metaCSVHeader = []string{
+	"resource", "type", "namespace", "count", "expired",
+	"cache_duration", "oldest_timestamp", "newest_timestamp",
+}
+
+func metaOutputRowToCSV(row metaOutputRow) []string {
+	return []string{
+		row.Resource,
+		row.Type,
+		row.Namespace,
+		strconv.FormatInt(row.Count, 10),
+		strconv.FormatInt(row.Expired, 10),
+		row.CacheDuration,
+		row.OldestTimestamp.Format(time.RFC3339),
+		row.NewestTimestamp.Format(time.RFC3339),
+	}
+}
+
+func renderMetaCSV(w io.Writer, sources []metaSource, noHeaders bool) error {
+	cw := csv.NewWriter(w)
+
+	if !noHeaders {
+		if err := cw.Write(metaCSVHeader); err != nil {
+			return err
+		}
+	}
+	for _, s := range sources {
+		if err := cw.Write(metaOutputRowToCSV(toOutputRow(s))); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeMetaRowFormatted writes a single source's row during --watch in the
+// chosen output format: one JSON object or YAML document per row for
+// json/yaml (so each line/document is independently parseable as rows
+// arrive), one CSV record via the shared csvWriter for csv, or the plain
+// table row otherwise.
+func writeMetaRowFormatted(w io.Writer, s metaSource, format string, csvWriter *csv.Writer) error {
+	switch format {
+	case "json":
+		return json.NewEncoder(w).Encode(toOutputRow(s))
+	case "yaml":
+		if _, err := fmt.Fprint(w, "---\n"); err != nil {
+			return err
+		}
+		return yaml.NewEncoder(w).Encode(toOutputRow(s))
+	case "csv":
+		if err := csvWriter.Write(metaOutputRowToCSV(toOutputRow(s))); err != nil {
+			return err
+		}
+		csvWriter.Flush()
+		return csvWriter.Error()
+	default:
+		writeMetaRow(w, s)
+		return nil
+	}
+}
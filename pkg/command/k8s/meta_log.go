@@ -0,0 +1,46 @@
+package k8s
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// discardLogger is used whenever neither Config.Logger nor --log-format/
+// --log-level was set, preserving this package's historical silent
+// behavior: no log output mixed into a command's stdout/stderr.
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// newLogHandler builds the slog.Handler backing --log-format/--log-level:
+// "json" for machine-readable log pipelines, anything else (including the
+// default "text") for humans reading a terminal.
+func newLogHandler(format string, level slog.Level, w io.Writer) (slog.Handler, error) {
+	opts := &slog.HandlerOptions{Level: level}
+	switch format {
+	case "json":
+		return slog.NewJSONHandler(w, opts), nil
+	case "text", "":
+		return slog.NewTextHandler(w, opts), nil
+	default:
+		return nil, fmt.Errorf("invalid --log-format %q: must be text or json", format)
+	}
+}
+
+// parseLogLevel maps --log-level's string values onto slog's levels.
+func parseLogLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("invalid --log-level %q: must be debug, info, warn, or error", level)
+	}
+}
@@ -0,0 +1,114 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net/url"
+	"time"
+)
+
+// metaServerError reports a 5xx status fetchMeta got back from Log Cache. It's its own type (rather than a plain fmt.Errorf) so
+// isRetryableMetaError can tell a 5xx apart from the other errors fetchMeta
+// can return.
+type metaServerError struct {
+	status int
+}
+
+func (e *metaServerError) Error() string {
+	return fmt.Sprintf("log cache meta request failed with status %d", e.status)
+}
+
+// isRetryableMetaError reports whether err is worth retrying: a 5xx
+// response, or a connection-level failure reaching the server. Context
+// cancellation/deadlines are never retryable, even when they surface
+// wrapped inside a *url.Error from http.Client.Do.
+func isRetryableMetaError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var serverErr *metaServerError
+	if errors.As(err, &serverErr) {
+		return true
+	}
+
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// fetchMetaWithRetry wraps fetchMeta with jittered exponential backoff: on a
+// retryable error, it sleeps an increasing, jittered interval starting at
+// baseBackoff before trying again, up to maxAttempts total attempts. A
+// cancelled or expired ctx aborts immediately, even mid-backoff, rather than
+// sleeping out the remainder. maxAttempts < 1 behaves like 1 (no retries),
+// the default before WithMetaRetry existed.
+func fetchMetaWithRetry(ctx context.Context, addr, lastEventID string, maxAttempts int, baseBackoff time.Duration, logger *slog.Logger) ([]metaSource, string, bool, error) {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		var sources []metaSource
+		var eventID string
+		var reset bool
+		sources, eventID, reset, err = fetchMeta(ctx, addr, lastEventID)
+		if err == nil {
+			return sources, eventID, reset, nil
+		}
+		if ctx.Err() != nil || !isRetryableMetaError(err) || attempt == maxAttempts {
+			return nil, "", false, err
+		}
+
+		backoff := jitteredBackoff(baseBackoff, attempt)
+		logger.Warn("meta.retry", "addr", addr, "attempt", attempt, "backoff", backoff, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return nil, "", false, ctx.Err()
+		case <-time.After(backoff):
+		}
+	}
+	return nil, "", false, err
+}
+
+// jitteredBackoff computes the attempt'th backoff interval using "equal
+// jitter": half the exponentially-scaled interval, plus a random amount up
+// to the other half. This avoids every retrying client waking up in
+// lockstep while still guaranteeing at least half the backoff elapses.
+// Intervals are capped at 30s so a large baseBackoff or attempt count can't
+// produce an effectively unbounded sleep.
+func jitteredBackoff(baseBackoff time.Duration, attempt int) time.Duration {
+	if baseBackoff <= 0 {
+		return 0
+	}
+
+	const maxBackoff = 30 * time.Second
+	scaled := baseBackoff * time.Duration(int64(1)<<uint(attempt-1))
+	if scaled <= 0 || scaled > maxBackoff {
+		scaled = maxBackoff
+	}
+
+	half := scaled / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// ExitCode maps an error a k8s command returned to the process exit code a
+// CLI entrypoint should report: 130 for user cancellation (matching the
+// conventional 128+SIGINT), 124 for a deadline exceeded (matching
+// timeout(1)'s convention), 0 for no error, or 1 for anything else.
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, context.Canceled):
+		return 130
+	case errors.Is(err, context.DeadlineExceeded):
+		return 124
+	default:
+		return 1
+	}
+}
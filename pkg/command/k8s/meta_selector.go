@@ -0,0 +1,184 @@
+package k8s
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Requirement is a single parsed --selector predicate, e.g. "namespace=foo"
+// or "type in (pod,deployment)".
+type Requirement struct {
+	key      string
+	operator string
+	values   []string
+}
+
+func (r Requirement) matches(labels map[string]string) bool {
+	v, ok := labels[r.key]
+	switch r.operator {
+	case "=", "==":
+		return ok && v == r.values[0]
+	case "!=":
+		return !ok || v != r.values[0]
+	case "in":
+		return ok && containsValue(r.values, v)
+	case "notin":
+		return !ok || !containsValue(r.values, v)
+	default:
+		return false
+	}
+}
+
+func containsValue(values []string, v string) bool {
+	for _, c := range values {
+		if c == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Selector is a parsed --selector expression: a conjunction (AND) of
+// Requirements, the same semantics kubectl's -l flag uses.
+type Selector struct {
+	requirements []Requirement
+}
+
+// Matches reports whether labels satisfies every requirement in sel. A zero
+// Selector (no requirements) matches everything, so an empty --selector
+// preserves the unfiltered behavior.
+func (sel Selector) Matches(labels map[string]string) bool {
+	for _, r := range sel.requirements {
+		if !r.matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseSelector parses a comma-separated, k8s-style selector expression:
+// "key=value", "key==value", "key!=value", "key in (a,b)", or
+// "key notin (a,b)". An empty (or all-whitespace) expr parses to the zero
+// Selector, which matches everything.
+func ParseSelector(expr string) (Selector, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return Selector{}, nil
+	}
+
+	clauses, err := splitRequirements(expr)
+	if err != nil {
+		return Selector{}, err
+	}
+
+	sel := Selector{requirements: make([]Requirement, 0, len(clauses))}
+	for _, clause := range clauses {
+		req, err := parseRequirement(clause)
+		if err != nil {
+			return Selector{}, err
+		}
+		sel.requirements = append(sel.requirements, req)
+	}
+	return sel, nil
+}
+
+// splitRequirements splits expr on top-level commas, i.e. commas outside of
+// an "in (...)"/"notin (...)" value list.
+func splitRequirements(expr string) ([]string, error) {
+	var clauses []string
+	depth := 0
+	start := 0
+	for i, r := range expr {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("selector %q has an unmatched ')'", expr)
+			}
+		case ',':
+			if depth == 0 {
+				clauses = append(clauses, expr[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("selector %q has an unmatched '('", expr)
+	}
+	clauses = append(clauses, expr[start:])
+
+	for i := range clauses {
+		clauses[i] = strings.TrimSpace(clauses[i])
+		if clauses[i] == "" {
+			return nil, fmt.Errorf("selector %q has an empty requirement", expr)
+		}
+	}
+	return clauses, nil
+}
+
+// parseRequirement parses a single clause like "key=value" or
+// "key in (a,b)".
+func parseRequirement(clause string) (Requirement, error) {
+	if idx := strings.Index(clause, "!="); idx >= 0 {
+		return requirementWithValue(clause, idx, 2, "!=")
+	}
+	if idx := strings.Index(clause, "=="); idx >= 0 {
+		return requirementWithValue(clause, idx, 2, "==")
+	}
+	if idx := strings.Index(clause, "="); idx >= 0 {
+		return requirementWithValue(clause, idx, 1, "=")
+	}
+
+	fields := strings.Fields(clause)
+	if len(fields) >= 2 && (fields[1] == "in" || fields[1] == "notin") {
+		keyEnd := len(fields[0])
+		opIdx := strings.Index(clause[keyEnd:], fields[1])
+		rest := strings.TrimSpace(clause[keyEnd+opIdx+len(fields[1]):])
+
+		values, err := parseValueSet(rest)
+		if err != nil {
+			return Requirement{}, fmt.Errorf("selector requirement %q: %s", clause, err)
+		}
+		return Requirement{key: fields[0], operator: fields[1], values: values}, nil
+	}
+
+	return Requirement{}, fmt.Errorf("selector requirement %q is not a valid key=value, key!=value, key in (...), or key notin (...) expression", clause)
+}
+
+func requirementWithValue(clause string, idx, opLen int, op string) (Requirement, error) {
+	key := strings.TrimSpace(clause[:idx])
+	value := strings.TrimSpace(clause[idx+opLen:])
+	if key == "" || value == "" {
+		return Requirement{}, fmt.Errorf("selector requirement %q is missing a key or value", clause)
+	}
+	if strings.ContainsAny(key, " \t()") || strings.ContainsAny(value, " \t()") {
+		return Requirement{}, fmt.Errorf("selector requirement %q has an invalid key or value", clause)
+	}
+	return Requirement{key: key, operator: op, values: []string{value}}, nil
+}
+
+// parseValueSet parses a parenthesized, comma-separated value list like
+// "(a,b,c)", the argument to "in"/"notin".
+func parseValueSet(s string) ([]string, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "(") || !strings.HasSuffix(s, ")") {
+		return nil, fmt.Errorf("expected a parenthesized, comma-separated value list like (a,b)")
+	}
+
+	inner := strings.TrimSpace(s[1 : len(s)-1])
+	if inner == "" {
+		return nil, fmt.Errorf("value list must not be empty")
+	}
+
+	var values []string
+	for _, v := range strings.Split(inner, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			return nil, fmt.Errorf("value list has an empty entry")
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
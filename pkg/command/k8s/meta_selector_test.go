@@ -0,0 +1,81 @@
+package k8s_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/log-cache-cli/pkg/command/k8s"
+)
+
+var _ = Describe("ParseSelector", func() {
+	labels := map[string]string{"namespace": "ns", "type": "pod", "name": "foo"}
+
+	It("matches everything for an empty expression", func() {
+		sel, err := k8s.ParseSelector("")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sel.Matches(labels)).To(BeTrue())
+	})
+
+	It("matches a key=value requirement", func() {
+		sel, err := k8s.ParseSelector("namespace=ns")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sel.Matches(labels)).To(BeTrue())
+
+		sel, err = k8s.ParseSelector("namespace=other")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sel.Matches(labels)).To(BeFalse())
+	})
+
+	It("matches a key!=value requirement", func() {
+		sel, err := k8s.ParseSelector("type!=deployment")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sel.Matches(labels)).To(BeTrue())
+	})
+
+	It("matches a key in (...) requirement", func() {
+		sel, err := k8s.ParseSelector("type in (pod, deployment)")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sel.Matches(labels)).To(BeTrue())
+
+		sel, err = k8s.ParseSelector("type in (deployment, statefulset)")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sel.Matches(labels)).To(BeFalse())
+	})
+
+	It("matches a key notin (...) requirement", func() {
+		sel, err := k8s.ParseSelector("type notin (deployment, statefulset)")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sel.Matches(labels)).To(BeTrue())
+	})
+
+	It("ANDs multiple comma-separated requirements together", func() {
+		sel, err := k8s.ParseSelector("namespace=ns,type!=deployment")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sel.Matches(labels)).To(BeTrue())
+
+		sel, err = k8s.ParseSelector("namespace=ns,type!=pod")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sel.Matches(labels)).To(BeFalse())
+	})
+
+	expectRejected := func(exprs []string) {
+		for _, expr := range exprs {
+			_, err := k8s.ParseSelector(expr)
+			Expect(err).To(HaveOccurred(), "expected %q to be rejected", expr)
+		}
+	}
+
+	It("rejects malformed expressions rather than silently matching nothing", func() {
+		expectRejected([]string{
+			"namespace",
+			"namespace=",
+			"=ns",
+			"type inn (pod)",
+			"type in pod",
+			"type in ()",
+			"type in (pod",
+			"namespace=ns,",
+			"namespace=ns,,type=pod",
+		})
+	})
+})
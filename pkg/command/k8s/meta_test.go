@@ -2,11 +2,13 @@ package k8s_test
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"time"
 
 	. "github.com/onsi/ginkgo"
@@ -74,6 +76,67 @@ var _ = Describe("Meta", func() {
 		Expect(buf.String()).To(BeEmpty())
 	})
 
+	It("prints a valid empty JSON array for zero sources, so a piped jq doesn't choke on empty stdin", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{}`)
+		}))
+		defer server.Close()
+		var buf bytes.Buffer
+		metaCmd := k8s.NewMeta(k8s.Config{
+			Addr: server.URL,
+		})
+		metaCmd.SetOutput(&buf)
+		metaCmd.SetArgs([]string{"--output", "json"})
+
+		err := metaCmd.Execute()
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(strings.TrimSpace(buf.String())).To(Equal("[]"))
+	})
+
+	It("prints a valid empty YAML list for zero sources", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{}`)
+		}))
+		defer server.Close()
+		var buf bytes.Buffer
+		metaCmd := k8s.NewMeta(k8s.Config{
+			Addr: server.URL,
+		})
+		metaCmd.SetOutput(&buf)
+		metaCmd.SetArgs([]string{"--output", "yaml"})
+
+		err := metaCmd.Execute()
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(strings.TrimSpace(buf.String())).To(Equal("[]"))
+	})
+
+	It("still prints just the header for zero sources in CSV, and nothing at all with WithMetaNoHeaders", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{}`)
+		}))
+		defer server.Close()
+
+		var buf bytes.Buffer
+		metaCmd := k8s.NewMeta(k8s.Config{
+			Addr: server.URL,
+		})
+		metaCmd.SetOutput(&buf)
+		metaCmd.SetArgs([]string{"--output", "csv"})
+		Expect(metaCmd.Execute()).To(Succeed())
+		Expect(strings.TrimSpace(buf.String())).To(Equal("resource,type,namespace,count,expired,cache_duration,oldest_timestamp,newest_timestamp"))
+
+		buf.Reset()
+		metaCmd = k8s.NewMeta(k8s.Config{
+			Addr: server.URL,
+		}, k8s.WithMetaNoHeaders())
+		metaCmd.SetOutput(&buf)
+		metaCmd.SetArgs([]string{"--output", "csv"})
+		Expect(metaCmd.Execute()).To(Succeed())
+		Expect(buf.String()).To(BeEmpty())
+	})
+
 	It("removes header when not writing to a tty", func() {
 		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			fmt.Fprint(w, metaResponseInfo(
@@ -143,6 +206,319 @@ var _ = Describe("Meta", func() {
 		Eventually(done, "500ms").Should(BeClosed())
 		Expect(err).To(MatchError(ContainSubstring("context deadline exceeded")))
 	})
+
+	It("prints rows as JSON with --output json", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, metaResponseInfo("ns/pod/foo"))
+		}))
+		defer server.Close()
+		var buf bytes.Buffer
+		metaCmd := k8s.NewMeta(k8s.Config{
+			Addr: server.URL,
+		})
+		metaCmd.SetOutput(&buf)
+		metaCmd.SetArgs([]string{"--output", "json"})
+
+		err := metaCmd.Execute()
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(buf.String()).To(ContainSubstring(`"resource": "foo"`))
+		Expect(buf.String()).To(ContainSubstring(`"namespace": "ns"`))
+		Expect(buf.String()).To(ContainSubstring(`"oldest_timestamp": "2018-02-21T23:47:43Z"`))
+	})
+
+	It("prints rows as YAML with --output yaml", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, metaResponseInfo("ns/pod/foo"))
+		}))
+		defer server.Close()
+		var buf bytes.Buffer
+		metaCmd := k8s.NewMeta(k8s.Config{
+			Addr: server.URL,
+		})
+		metaCmd.SetOutput(&buf)
+		metaCmd.SetArgs([]string{"--output", "yaml"})
+
+		err := metaCmd.Execute()
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(buf.String()).To(ContainSubstring("resource: foo"))
+		Expect(buf.String()).To(ContainSubstring("namespace: ns"))
+	})
+
+	It("prints rows as CSV with --output csv, and omits the header with WithMetaNoHeaders", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, metaResponseInfo("ns/pod/foo"))
+		}))
+		defer server.Close()
+		var buf bytes.Buffer
+		metaCmd := k8s.NewMeta(k8s.Config{
+			Addr: server.URL,
+		}, k8s.WithMetaNoHeaders())
+		metaCmd.SetOutput(&buf)
+		metaCmd.SetArgs([]string{"--output", "csv"})
+
+		err := metaCmd.Execute()
+
+		Expect(err).ToNot(HaveOccurred())
+		lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+		Expect(lines).To(HaveLen(1))
+		Expect(lines[0]).To(Equal("foo,pod,ns,100000,99999,1s,2018-02-21T23:47:43Z,2018-02-21T23:47:43Z"))
+	})
+
+	It("filters rows by --namespace and --resource-type", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, metaResponseInfo(
+				"source-id-1",
+				"ns/pod/foo",
+				"ns/deployment/foo",
+				"ns2/pod/foo",
+			))
+		}))
+		defer server.Close()
+
+		var buf bytes.Buffer
+		metaCmd := k8s.NewMeta(k8s.Config{
+			Addr: server.URL,
+		}, k8s.WithMetaNoHeaders())
+		metaCmd.SetOutput(&buf)
+		metaCmd.SetArgs([]string{"--namespace", "ns", "--resource-type", "pod"})
+
+		err := metaCmd.Execute()
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(strings.Split(buf.String(), "\n")).To(Equal([]string{
+			"foo   pod   ns   100000   85008   11m45s",
+			"",
+		}))
+	})
+
+	It("combines a WithMetaSelector option with the --selector flag", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, metaResponseInfo("ns/deployment/foo", "ns/pod/foo", "ns2/pod/bar"))
+		}))
+		defer server.Close()
+
+		sel, err := k8s.ParseSelector("namespace=ns")
+		Expect(err).ToNot(HaveOccurred())
+
+		var buf bytes.Buffer
+		metaCmd := k8s.NewMeta(k8s.Config{
+			Addr: server.URL,
+		}, k8s.WithMetaNoHeaders(), k8s.WithMetaSelector(sel))
+		metaCmd.SetOutput(&buf)
+		metaCmd.SetArgs([]string{"--selector", "type=pod"})
+
+		err = metaCmd.Execute()
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(strings.Split(buf.String(), "\n")).To(Equal([]string{
+			"foo   pod   ns   100000   85008   11m45s",
+			"",
+		}))
+	})
+
+	It("rejects a malformed --selector", func() {
+		metaCmd := k8s.NewMeta(k8s.Config{
+			Addr: "http://127.0.0.1:0",
+		})
+		var buf bytes.Buffer
+		metaCmd.SetOutput(&buf)
+		metaCmd.SetArgs([]string{"--selector", "namespace"})
+
+		err := metaCmd.Execute()
+
+		Expect(err).To(MatchError(ContainSubstring("invalid --selector")))
+	})
+
+	It("rejects an unknown --output format", func() {
+		metaCmd := k8s.NewMeta(k8s.Config{
+			Addr: "http://127.0.0.1:0",
+		})
+		var buf bytes.Buffer
+		metaCmd.SetOutput(&buf)
+		metaCmd.SetArgs([]string{"--output", "xml"})
+
+		err := metaCmd.Execute()
+
+		Expect(err).To(MatchError(ContainSubstring(`invalid --output "xml"`)))
+	})
+
+	It("stays silent by default even on a timeout, since no logger was configured", func() {
+		done := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-time.After(time.Second):
+			case <-done:
+			}
+		}))
+		defer server.Close()
+		metaCmd := k8s.NewMeta(k8s.Config{
+			Addr: server.URL,
+		}, k8s.WithMetaTimeout(time.Nanosecond))
+		var out, errOut bytes.Buffer
+		metaCmd.SetOut(&out)
+		metaCmd.SetErr(&errOut)
+		metaCmd.SetArgs([]string{})
+
+		var err error
+		go func() {
+			defer close(done)
+			err = metaCmd.Execute()
+		}()
+
+		Eventually(done, "500ms").Should(BeClosed())
+		Expect(err).To(MatchError(ContainSubstring("context deadline exceeded")))
+		Expect(errOut.String()).To(BeEmpty())
+	})
+
+	It("logs structured events to stderr when --log-level is set", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, metaResponseInfo("source-id-1"))
+		}))
+		defer server.Close()
+		metaCmd := k8s.NewMeta(k8s.Config{
+			Addr: server.URL,
+		})
+		var out, errOut bytes.Buffer
+		metaCmd.SetOut(&out)
+		metaCmd.SetErr(&errOut)
+		metaCmd.SetArgs([]string{"--log-level", "info", "--log-format", "json"})
+
+		err := metaCmd.Execute()
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(errOut.String()).To(ContainSubstring(`"msg":"meta.fetch"`))
+		Expect(errOut.String()).To(ContainSubstring(`"msg":"meta.fetch.complete"`))
+		Expect(out.String()).ToNot(ContainSubstring("meta.fetch"))
+	})
+
+	It("rejects an unknown --log-format", func() {
+		metaCmd := k8s.NewMeta(k8s.Config{
+			Addr: "http://127.0.0.1:0",
+		})
+		var buf bytes.Buffer
+		metaCmd.SetOutput(&buf)
+		metaCmd.SetArgs([]string{"--log-format", "xml"})
+
+		err := metaCmd.Execute()
+
+		Expect(err).To(MatchError(ContainSubstring(`invalid --log-format "xml"`)))
+	})
+
+	It("retries a flaky server that returns 503 twice before succeeding", func() {
+		var mu sync.Mutex
+		var requestCount int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			requestCount++
+			n := requestCount
+			mu.Unlock()
+
+			if n <= 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprint(w, metaResponseInfo("source-id-1"))
+		}))
+		defer server.Close()
+
+		var buf bytes.Buffer
+		metaCmd := k8s.NewMeta(k8s.Config{
+			Addr: server.URL,
+		}, k8s.WithMetaNoHeaders(), k8s.WithMetaRetry(5, time.Millisecond))
+		metaCmd.SetOutput(&buf)
+		metaCmd.SetArgs([]string{})
+
+		err := metaCmd.Execute()
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(buf.String()).To(ContainSubstring("source-id-1"))
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(requestCount).To(Equal(3))
+	})
+
+	It("aborts immediately on cancellation mid-retry, without sleeping out the backoff", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		metaCmd := k8s.NewMeta(k8s.Config{
+			Addr: server.URL,
+		}, k8s.WithMetaContext(ctx), k8s.WithMetaRetry(100, time.Hour))
+		var buf bytes.Buffer
+		metaCmd.SetOutput(&buf)
+		metaCmd.SetArgs([]string{})
+
+		time.AfterFunc(20*time.Millisecond, cancel)
+
+		start := time.Now()
+		err := metaCmd.Execute()
+		elapsed := time.Since(start)
+
+		Expect(err).To(MatchError(context.Canceled))
+		Expect(k8s.ExitCode(err)).To(Equal(130))
+		Expect(elapsed).To(BeNumerically("<", time.Second))
+	})
+
+	It("maps a deadline-exceeded error to exit code 124", func() {
+		Expect(k8s.ExitCode(context.DeadlineExceeded)).To(Equal(124))
+	})
+
+	It("resumes with --watch using the last event ID, and restarts cleanly on a 410", func() {
+		var mu sync.Mutex
+		var requestCount int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			requestCount++
+			n := requestCount
+			lastEventID := r.Header.Get("Last-Event-ID")
+			mu.Unlock()
+
+			switch {
+			case n == 1:
+				Expect(lastEventID).To(BeEmpty())
+				w.Header().Set("X-Log-Cache-Event-Id", "1")
+				fmt.Fprint(w, metaResponseInfo("source-id-a"))
+			case n == 2:
+				Expect(lastEventID).To(Equal("1"))
+				w.WriteHeader(http.StatusGone)
+			default:
+				Expect(lastEventID).To(BeEmpty(), "a reset should drop the stale event ID")
+				w.Header().Set("X-Log-Cache-Event-Id", "2")
+				fmt.Fprint(w, metaResponseInfo("source-id-b"))
+			}
+		}))
+		defer server.Close()
+
+		var buf bytes.Buffer
+		metaCmd := k8s.NewMeta(k8s.Config{
+			Addr: server.URL,
+		}, k8s.WithMetaNoHeaders(), k8s.WithMetaWatchInterval(time.Millisecond))
+		metaCmd.SetOutput(&buf)
+		metaCmd.SetArgs([]string{"--watch"})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			metaCmd.ExecuteContext(ctx)
+		}()
+
+		Eventually(done, "1s").Should(BeClosed())
+
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(requestCount).To(BeNumerically(">=", 3))
+		Expect(buf.String()).To(ContainSubstring("reset: resuming from current tail"))
+		Expect(buf.String()).To(ContainSubstring("source-id-a"))
+		Expect(buf.String()).To(ContainSubstring("source-id-b"))
+	})
 })
 
 func metaResponseInfo(sourceIDs ...string) string {